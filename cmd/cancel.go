@@ -9,24 +9,44 @@ import (
 )
 
 var cancelCmd = &cobra.Command{
-	Use:   "cancel <workflow-id>",
-	Short: "cancels a running workflow",
-	Args:  cobra.ExactArgs(1),
-	RunE:  cancel,
+	Use:         "cancel <workflow-id>",
+	Short:       "cancels a running workflow",
+	Args:        cobra.ExactArgs(1),
+	RunE:        cancel,
+	Annotations: map[string]string{logLevelAnnotation: internal.LogLevelInfo},
 }
 
 func init() {
+	addOutputFlags(cancelCmd, internal.OutputFormatDetail, "")
 	rootCmd.AddCommand(cancelCmd)
 }
 
 func cancel(cmd *cobra.Command, args []string) error {
-	config, err := internal.ServiceConfigFromFlags(cmd.Flags())
+	ctx, cancel, err := newContextFromFlags(cmd)
 
 	if err != nil {
 		return err
 	}
 
-	client := internal.NewClient(config.BaseURL, config.AccessKey)
+	defer cancel()
+
+	client, err := newClientFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	outputOptions, err := outputOptionsFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := internal.NewWorkflowWriter(outputOptions)
+
+	if err != nil {
+		return err
+	}
 
 	rawWorkflowID, err := strconv.Atoi(args[0])
 
@@ -38,13 +58,11 @@ func cancel(cmd *cobra.Command, args []string) error {
 
 	slog.Info("cancel", "workflowID", workflowID)
 
-	workflow, err := internal.CancelWorkflow(client, workflowID)
+	workflow, err := internal.CancelWorkflowContext(ctx, client, workflowID)
 
 	if err != nil {
 		return err
 	}
 
-	printWorkflow(workflow)
-
-	return nil
+	return writer.WriteWorkflow(cmd.OutOrStdout(), workflow)
 }
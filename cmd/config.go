@@ -0,0 +1,484 @@
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stjudecloud/msgenctl/internal"
+	"golang.org/x/term"
+)
+
+const defaultProfileName = "default"
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "manages configuration profiles",
+	// Only the environment is applied, as the profile may not exist yet.
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := internal.ApplyEnv(cmd.Flags()); err != nil {
+			return err
+		}
+
+		return setUpLogging(cmd)
+	},
+}
+
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "interactively creates a profile",
+	Args:  cobra.NoArgs,
+	RunE:  configInit,
+}
+
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "sets a profile value",
+	Args:  cobra.ExactArgs(2),
+	RunE:  configSet,
+}
+
+var configGetCmd = &cobra.Command{
+	Use:   "get <key>",
+	Short: "prints a profile value",
+	Args:  cobra.ExactArgs(1),
+	RunE:  configGet,
+}
+
+var configUnsetCmd = &cobra.Command{
+	Use:   "unset <key>",
+	Short: "removes a profile value",
+	Args:  cobra.ExactArgs(1),
+	RunE:  configUnset,
+}
+
+var configListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "prints all profile values with secrets masked",
+	Args:  cobra.NoArgs,
+	RunE:  configList,
+}
+
+var configUseProfileCmd = &cobra.Command{
+	Use:   "use-profile <name>",
+	Short: "sets the current profile",
+	Args:  cobra.ExactArgs(1),
+	RunE:  configUseProfile,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "checks a profile for errors",
+	Args:  cobra.NoArgs,
+	RunE:  configValidate,
+}
+
+// initKeys are the keys prompted for by `config init`.
+var initKeys = []string{
+	"base-url",
+	"access-key",
+	"input-storage-auth",
+	"input-storage-connection-string",
+	"input-storage-container-name",
+	"output-storage-auth",
+	"output-storage-connection-string",
+	"output-storage-container-name",
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+	configCmd.AddCommand(configSetCmd)
+	configCmd.AddCommand(configGetCmd)
+	configCmd.AddCommand(configUnsetCmd)
+	configCmd.AddCommand(configListCmd)
+	configCmd.AddCommand(configUseProfileCmd)
+	configCmd.AddCommand(configValidateCmd)
+
+	rootCmd.AddCommand(configCmd)
+}
+
+func configInit(cmd *cobra.Command, args []string) error {
+	path, config, err := loadConfigFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	name, err := cmd.Flags().GetString("profile")
+
+	if err != nil {
+		return err
+	}
+
+	stdin := bufio.NewReader(cmd.InOrStdin())
+	stdout := cmd.OutOrStdout()
+
+	if len(name) == 0 {
+		name, err = prompt(stdin, stdout, fmt.Sprintf("profile [%s]", defaultProfileName), false)
+
+		if err != nil {
+			return err
+		}
+
+		if len(name) == 0 {
+			name = defaultProfileName
+		}
+	}
+
+	profile, ok := config.Profiles[name]
+
+	if !ok {
+		profile = internal.Profile{}
+	}
+
+	for _, key := range initKeys {
+		label := key
+
+		if value, ok := profile[key]; ok {
+			if isSecretKey(key) {
+				value = internal.MaskSecret(value)
+			}
+
+			label = fmt.Sprintf("%s [%s]", key, value)
+		}
+
+		value, err := prompt(stdin, stdout, label, isSecretKey(key))
+
+		if err != nil {
+			return err
+		}
+
+		if len(value) > 0 {
+			profile[key] = value
+		}
+	}
+
+	if err := internal.ValidateProfile(profile); err != nil {
+		return err
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = map[string]internal.Profile{}
+	}
+
+	config.Profiles[name] = profile
+
+	if len(config.CurrentProfile) == 0 {
+		config.CurrentProfile = name
+	}
+
+	if err := internal.SaveConfig(path, config); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(stdout, "wrote profile %q to %s\n", name, path)
+
+	return nil
+}
+
+func configSet(cmd *cobra.Command, args []string) error {
+	key, value := args[0], args[1]
+
+	if err := checkConfigKey(key); err != nil {
+		return err
+	}
+
+	path, config, err := loadConfigFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	name, err := profileNameFromFlags(cmd.Flags(), config)
+
+	if err != nil {
+		return err
+	}
+
+	if len(name) == 0 {
+		name = defaultProfileName
+	}
+
+	if config.Profiles == nil {
+		config.Profiles = map[string]internal.Profile{}
+	}
+
+	if _, ok := config.Profiles[name]; !ok {
+		config.Profiles[name] = internal.Profile{}
+	}
+
+	config.Profiles[name][key] = value
+
+	if len(config.CurrentProfile) == 0 {
+		config.CurrentProfile = name
+	}
+
+	return internal.SaveConfig(path, config)
+}
+
+func configGet(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	_, profile, err := loadProfileFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	value, ok := profile[key]
+
+	if !ok {
+		return fmt.Errorf("key not set: %s", key)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), value)
+
+	return nil
+}
+
+func configUnset(cmd *cobra.Command, args []string) error {
+	key := args[0]
+
+	path, config, err := loadConfigFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	name, err := profileNameFromFlags(cmd.Flags(), config)
+
+	if err != nil {
+		return err
+	}
+
+	profile, err := config.Profile(name)
+
+	if err != nil {
+		return err
+	}
+
+	if _, ok := profile[key]; !ok {
+		return fmt.Errorf("key not set: %s", key)
+	}
+
+	delete(profile, key)
+
+	return internal.SaveConfig(path, config)
+}
+
+func configList(cmd *cobra.Command, args []string) error {
+	name, profile, err := loadProfileFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	stdout := cmd.OutOrStdout()
+
+	fmt.Fprintf(stdout, "# profile: %s\n", name)
+
+	keys := make([]string, 0, len(profile))
+
+	for key := range profile {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := profile[key]
+
+		if isSecretKey(key) {
+			value = internal.MaskSecret(value)
+		}
+
+		fmt.Fprintf(stdout, "%s=%s\n", key, value)
+	}
+
+	return nil
+}
+
+func configUseProfile(cmd *cobra.Command, args []string) error {
+	name := args[0]
+
+	path, config, err := loadConfigFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := config.Profile(name); err != nil {
+		return err
+	}
+
+	config.CurrentProfile = name
+
+	return internal.SaveConfig(path, config)
+}
+
+func configValidate(cmd *cobra.Command, args []string) error {
+	name, profile, err := loadProfileFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(profile))
+
+	for key := range profile {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	errs := []error{}
+
+	for _, key := range keys {
+		if err := checkConfigKey(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if err := internal.ValidateProfile(profile); err != nil {
+		errs = append(errs, err)
+	}
+
+	if err := errors.Join(errs...); err != nil {
+		return fmt.Errorf("invalid profile %q:\n%w", name, err)
+	}
+
+	fmt.Fprintf(cmd.OutOrStdout(), "profile %q is valid\n", name)
+
+	return nil
+}
+
+func loadConfigFromFlags(flags *pflag.FlagSet) (string, internal.Config, error) {
+	path, err := flags.GetString("config")
+
+	if err != nil {
+		return "", internal.Config{}, err
+	}
+
+	config, err := internal.LoadConfig(path)
+
+	return path, config, err
+}
+
+// profileNameFromFlags returns the name of the profile given by `--profile`,
+// falling back to the current profile.
+func profileNameFromFlags(flags *pflag.FlagSet, config internal.Config) (string, error) {
+	name, err := flags.GetString("profile")
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(name) == 0 {
+		name = config.CurrentProfile
+	}
+
+	return name, nil
+}
+
+func loadProfileFromFlags(flags *pflag.FlagSet) (string, internal.Profile, error) {
+	_, config, err := loadConfigFromFlags(flags)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	name, err := profileNameFromFlags(flags, config)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	if len(name) == 0 {
+		return "", nil, errors.New("no profile selected: use --profile or `config use-profile`")
+	}
+
+	profile, err := config.Profile(name)
+
+	return name, profile, err
+}
+
+// configKeys returns all flags that can be set in a profile, i.e., every flag
+// of every command except those that select the configuration itself.
+func configKeys() map[string]*pflag.Flag {
+	keys := map[string]*pflag.Flag{}
+
+	var visit func(cmd *cobra.Command)
+
+	visit = func(cmd *cobra.Command) {
+		cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+			switch flag.Name {
+			case "config", "profile", "help", "version":
+			default:
+				keys[flag.Name] = flag
+			}
+		})
+
+		for _, subcommand := range cmd.Commands() {
+			visit(subcommand)
+		}
+	}
+
+	visit(rootCmd)
+
+	return keys
+}
+
+func checkConfigKey(key string) error {
+	if _, ok := configKeys()[key]; !ok {
+		return fmt.Errorf("unknown key: %s", key)
+	}
+
+	return nil
+}
+
+// isSecretKey returns whether the key is a secret flag, e.g., `access-key`,
+// but not its `-file` or `-command` variants.
+func isSecretKey(key string) bool {
+	flag, ok := configKeys()[key]
+
+	if !ok {
+		return false
+	}
+
+	group, ok := flag.Annotations[internal.SecretSourceAnnotation]
+
+	return ok && group[0] == key
+}
+
+// prompt prints the label and reads a line from stdin.
+//
+// Secrets are read without echo when stdin is a terminal.
+func prompt(stdin *bufio.Reader, stdout io.Writer, label string, secret bool) (string, error) {
+	fmt.Fprintf(stdout, "%s: ", label)
+
+	fd := int(os.Stdin.Fd())
+
+	if secret && term.IsTerminal(fd) {
+		line, err := term.ReadPassword(fd)
+		fmt.Fprintln(stdout)
+		return strings.TrimSpace(string(line)), err
+	}
+
+	line, err := stdin.ReadString('\n')
+
+	if err != nil && !(errors.Is(err, io.EOF) && len(line) > 0) {
+		if errors.Is(err, io.EOF) {
+			return "", nil
+		}
+
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
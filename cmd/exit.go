@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+// Exit codes. These are documented in the README and must not change.
+const (
+	exitCodeSuccess           = 0
+	exitCodeError             = 1
+	exitCodeAuth              = 3
+	exitCodeNotFound          = 4
+	exitCodeQuota             = 5
+	exitCodeValidation        = 6
+	exitCodeTransport         = 7
+	exitCodeWorkflowFailed    = 8
+	exitCodeWorkflowCancelled = 9
+	exitCodeTimeout           = 10
+	exitCodeInterrupted       = 130
+)
+
+// exitCode maps an error returned by a command to the process exit code.
+func exitCode(err error) int {
+	if err == nil {
+		return exitCodeSuccess
+	}
+
+	var apiErr *internal.APIError
+	var workflowErr *internal.UnsuccessfulWorkflowError
+	var validationErrs internal.ValidationErrors
+	var authErr *azidentity.AuthenticationFailedError
+	var responseErr *azcore.ResponseError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &workflowErr):
+		if workflowErr.Status == internal.StatusCancelled {
+			return exitCodeWorkflowCancelled
+		}
+
+		return exitCodeWorkflowFailed
+	case errors.As(err, &apiErr):
+		return exitCodeForStatus(apiErr.StatusCode)
+	case errors.As(err, &validationErrs):
+		return exitCodeValidation
+	case errors.As(err, &authErr):
+		return exitCodeAuth
+	case errors.As(err, &responseErr):
+		return exitCodeForStatus(responseErr.StatusCode)
+	case errors.Is(err, context.DeadlineExceeded):
+		return exitCodeTimeout
+	case errors.Is(err, context.Canceled):
+		return exitCodeInterrupted
+	case errors.As(err, &netErr):
+		return exitCodeTransport
+	default:
+		return exitCodeError
+	}
+}
+
+func exitCodeForStatus(statusCode int) int {
+	switch {
+	case statusCode == http.StatusUnauthorized, statusCode == http.StatusForbidden:
+		return exitCodeAuth
+	case statusCode == http.StatusNotFound:
+		return exitCodeNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return exitCodeQuota
+	case statusCode == http.StatusBadRequest:
+		return exitCodeValidation
+	case statusCode >= http.StatusInternalServerError:
+		return exitCodeTransport
+	default:
+		return exitCodeError
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+// addFilterFlags adds the flags to select workflows (see
+// `internal.WorkflowFilterFromFlags`). The verb is what the command does with
+// the selected workflows, e.g., "list".
+func addFilterFlags(cmd *cobra.Command, verb string) {
+	flags := cmd.Flags()
+
+	flags.StringSlice("status", nil, fmt.Sprintf("%s only workflows with one of the statuses: %s", verb, strings.Join(internal.StatusNames, ", ")))
+	flags.Bool("failed-only", false, fmt.Sprintf("%s only failed workflows", verb))
+	flags.String("process", "", fmt.Sprintf("%s only workflows with a process matching the glob or /regexp/", verb))
+	flags.String("description", "", fmt.Sprintf("%s only workflows with a description matching the glob or /regexp/", verb))
+	flags.String("since", "", fmt.Sprintf("%s only workflows created at or after the time: a date (2024-01-01), month (2024-01), timestamp (2024-01-01T12:00:00Z), or duration ago (7d, 36h)", verb))
+	flags.String("until", "", fmt.Sprintf("%s only workflows created before the time (same formats as --since)", verb))
+	flags.String("id-range", "", fmt.Sprintf("%s only workflows with IDs in the inclusive range (e.g., 100-200, 100-, or -200)", verb))
+
+	// Filters are per query, and `--description` would otherwise share its
+	// profile value and environment variable with `submit`.
+	markCommandLineOnly(flags, "status", "failed-only", "process", "description", "since", "until", "id-range")
+}
+
+// markCommandLineOnly marks the flags as only read from the command line (see
+// `internal.CommandLineOnlyAnnotation`).
+func markCommandLineOnly(flags *pflag.FlagSet, names ...string) {
+	for _, name := range names {
+		flags.SetAnnotation(name, internal.CommandLineOnlyAnnotation, []string{"true"})
+	}
+}
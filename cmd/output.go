@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+// addOutputFlags adds the flags to select how the workflows printed by the
+// command are formatted: `-o/--output`, `--template`, and `--jsonpath`.
+func addOutputFlags(cmd *cobra.Command, defaultFormat string, usage string) {
+	flags := cmd.Flags()
+
+	formats := strings.Join(internal.OutputFormatNames, ", ")
+	flags.StringP("output", "o", defaultFormat, fmt.Sprintf("output format: %s%s", formats, usage))
+	flags.String("template", "", "Go template to format each workflow, e.g., '{{.ID}}\\t{{.Status}}'")
+	flags.String("jsonpath", "", "JSONPath template to format the workflows, e.g., '{.items[*].id}'")
+
+	// A template is per invocation and overrides an output format from an
+	// environment variable or profile (see `checkOutputFlags`).
+	markCommandLineOnly(flags, "template", "jsonpath")
+	cmd.MarkFlagsMutuallyExclusive("template", "jsonpath")
+}
+
+// checkOutputFlags checks that `--output` is not given on the command line
+// with `--template` or `--jsonpath`. It must run before other flag sources
+// are applied, as an output format from them is ignored instead.
+func checkOutputFlags(flags *pflag.FlagSet) error {
+	if flags.Lookup("template") == nil || !flags.Changed("output") {
+		return nil
+	}
+
+	for _, name := range []string{"template", "jsonpath"} {
+		if flags.Changed(name) {
+			return fmt.Errorf("--output and --%s cannot be given together", name)
+		}
+	}
+
+	return nil
+}
+
+// outputOptionsFromFlags returns the output options from the flags and the
+// failure codes from the configuration file. The output format is ignored if
+// a template or JSONPath is given, e.g., when it is set by an environment
+// variable or profile.
+func outputOptionsFromFlags(flags *pflag.FlagSet) (internal.OutputOptions, error) {
+	options := internal.OutputOptions{}
+
+	_, config, err := loadConfigFromFlags(flags)
+
+	if err != nil {
+		return options, err
+	}
+
+	options.FailureCodes = config.FailureCodes
+
+	template, err := flags.GetString("template")
+
+	if err != nil {
+		return options, err
+	}
+
+	jsonPath, err := flags.GetString("jsonpath")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Template = template
+	options.JSONPath = jsonPath
+
+	if len(template) > 0 || len(jsonPath) > 0 {
+		return options, nil
+	}
+
+	rawFormat, err := flags.GetString("output")
+
+	if err != nil || len(rawFormat) == 0 {
+		return options, err
+	}
+
+	format, err := internal.ParseOutputFormat(rawFormat)
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Format = format
+
+	return options, nil
+}
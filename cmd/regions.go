@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+var regionsCmd = &cobra.Command{
+	Use:   "regions",
+	Short: "lists supported regions and their base URLs",
+	Args:  cobra.NoArgs,
+	RunE:  regions,
+}
+
+func init() {
+	rootCmd.AddCommand(regionsCmd)
+}
+
+func regions(cmd *cobra.Command, args []string) error {
+	_, config, err := loadConfigFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	regions := internal.DefaultRegions().Merge(config.Regions)
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "REGION\tBASE URL")
+
+	for _, name := range regions.Names() {
+		fmt.Fprintf(w, "%s\t%s\n", name, regions[name])
+	}
+
+	return w.Flush()
+}
@@ -1,23 +1,83 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stjudecloud/msgenctl/internal"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var rootCmd = &cobra.Command{
-	Version:      internal.Version,
-	Use:          "msgenctl",
-	Short:        "Query and send commands to Microsoft Genomics",
-	SilenceUsage: true,
+	Version:           internal.Version,
+	Use:               "msgenctl",
+	Short:             "Query and send commands to Microsoft Genomics",
+	SilenceUsage:      true,
+	SilenceErrors:     true,
+	PersistentPreRunE: applyFlagSources,
 }
 
+// logLevelAnnotation is the command annotation that sets the default log
+// level of the command. Commands without it default to `warn`, so read-only
+// commands are quiet unless `--log-level` is given.
+const logLevelAnnotation = "msgenctl_log_level"
+
+// logFile is the log file opened by `setUpLogging`, if any. It is closed by
+// `Execute`.
+var logFile io.Closer
+
+// commandSpan traces the command and shutdownTracing flushes its spans. They
+// are set by `setUpTracing` and ended by `Execute`.
+var (
+	commandSpan     trace.Span
+	shutdownTracing func(context.Context) error
+)
+
+// tracingShutdownTimeout limits the time spent exporting pending spans on
+// exit.
+const tracingShutdownTimeout = 5 * time.Second
+
+// Execute runs the root command and exits with the code of its error, if any
+// (see `exitCode`). Secrets are redacted from logs and the error message. An
+// interrupt (SIGINT) or termination (SIGTERM) signal cancels in-flight
+// requests and retry waits. A second signal has its default behavior, i.e.,
+// it terminates the process, e.g., if it is stuck in something that does not
+// stop on cancellation, such as a credential helper command.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+	annotateEnvVars(rootCmd)
+
+	slog.SetDefault(slog.New(internal.NewRedactingHandler(slog.NewTextHandler(os.Stderr, nil))))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+
+	// Restore the default signal behavior once the first signal is received.
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
+	err := rootCmd.ExecuteContext(ctx)
+	stop()
+
+	endTracing(err)
+
+	if logFile != nil {
+		logFile.Close()
 	}
+
+	if err != nil {
+		rootCmd.PrintErrln("Error:", internal.Redact(err.Error()))
+	}
+
+	os.Exit(exitCode(err))
 }
 
 func init() {
@@ -26,9 +86,283 @@ func init() {
 
 	persistentFlags := rootCmd.PersistentFlags()
 
+	defaultConfigPath, _ := internal.DefaultConfigPath()
+	persistentFlags.String("config", defaultConfigPath, "configuration file")
+	persistentFlags.String("profile", "", "configuration profile (default is the current profile)")
+
 	persistentFlags.String("base-url", "", "Microsoft Genomics API base URL")
-	rootCmd.MarkPersistentFlagRequired("base-url")
+	persistentFlags.String("region", "", "Microsoft Genomics region, used to build the base URL")
+	addSecretFlags(rootCmd, persistentFlags, "access-key", "Microsoft Genomics API access key")
+
+	persistentFlags.Duration("timeout", 0, "maximum duration of the command, e.g., 30s or 2h (0 for no limit)")
+
+	options := internal.DefaultClientOptions()
+	persistentFlags.Int("max-retries", options.RetryMax, "maximum number of retries of a failed request")
+	persistentFlags.Duration("retry-wait-min", options.RetryWaitMin, "minimum wait between retries")
+	persistentFlags.Duration("retry-wait-max", options.RetryWaitMax, "maximum wait between retries")
+	persistentFlags.IntSlice("retry-status-codes", options.RetryableStatusCodes, "response status codes to retry")
+	persistentFlags.Duration("request-timeout", options.RequestTimeout, "timeout of each request attempt (0 for no limit)")
+	persistentFlags.Float64("rate-limit", options.RateLimit, "maximum number of requests per second (0 for no limit)")
+	persistentFlags.Int("rate-limit-burst", options.RateLimitBurst, "maximum number of requests sent at once")
+
+	addSecretFlags(rootCmd, persistentFlags, "proxy", "proxy URL for all requests (default from HTTPS_PROXY)")
+	persistentFlags.String("ca-bundle", "", "PEM file of CA certificates to trust in addition to the system certificates")
+	persistentFlags.String("client-cert", "", "PEM file of a client certificate for mutual TLS")
+	persistentFlags.String("client-key", "", "PEM file of the client certificate private key")
+	persistentFlags.Bool("insecure-skip-verify", false, "disable TLS certificate verification (insecure; testing only)")
+	rootCmd.MarkFlagsRequiredTogether("client-cert", "client-key")
+
+	persistentFlags.String("record", "", "directory to record redacted API requests and responses to")
+	persistentFlags.String("replay", "", "directory to replay recorded API responses from, without a network")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+
+	persistentFlags.String("log-level", "", "log level: debug, info, warn, error, or off (default info for commands that make changes, warn otherwise)")
+	persistentFlags.String("log-format", internal.LogFormatText, "log format: text or json")
+	persistentFlags.String("log-file", "", "file to append logs to (default stderr)")
+
+	persistentFlags.String("trace-file", "", "file to append trace spans to as JSON (see also OTEL_* variables)")
+}
+
+// setUpLogging sets the default logger from the log flags (see
+// `logLevelAnnotation`).
+func setUpLogging(cmd *cobra.Command) error {
+	var defaultLevel internal.LogLevel = internal.LogLevelWarn
+
+	if level, ok := cmd.Annotations[logLevelAnnotation]; ok {
+		defaultLevel = internal.LogLevel(level)
+	}
+
+	options, err := internal.LogOptionsFromFlags(cmd.Flags(), defaultLevel)
+
+	if err != nil {
+		return err
+	}
+
+	logger, closer, err := internal.NewLogger(options)
+
+	if err != nil {
+		return err
+	}
+
+	slog.SetDefault(logger)
+	logFile = closer
+
+	return nil
+}
+
+// setUpTracing sets up tracing (see `internal.SetUpTracing`) and starts a span
+// for the command, which is set as the parent of the spans of the command.
+func setUpTracing(cmd *cobra.Command) error {
+	file, err := cmd.Flags().GetString("trace-file")
+
+	if err != nil {
+		return err
+	}
+
+	shutdown, err := internal.SetUpTracing(cmd.Context(), internal.TraceOptions{File: file})
+
+	if err != nil {
+		return err
+	}
+
+	shutdownTracing = shutdown
+
+	ctx, span := internal.StartSpan(cmd.Context(), cmd.CommandPath())
+	commandSpan = span
+	cmd.SetContext(ctx)
+
+	return nil
+}
+
+// endTracing ends the command span with the command error, if any, and
+// flushes pending spans.
+func endTracing(err error) {
+	if commandSpan != nil {
+		internal.EndSpan(commandSpan, err)
+	}
+
+	if shutdownTracing == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Warn("could not export trace spans", "error", err)
+	}
+}
+
+// newContextFromFlags returns the command context limited by `--timeout`, if
+// set. The returned cancel function must be called when the command is done.
+func newContextFromFlags(cmd *cobra.Command) (context.Context, context.CancelFunc, error) {
+	ctx := cmd.Context()
+
+	timeout, err := cmd.Flags().GetDuration("timeout")
+
+	if err != nil {
+		return ctx, func() {}, err
+	}
+
+	if timeout <= 0 {
+		ctx, cancel := context.WithCancel(ctx)
+		return ctx, cancel, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 
-	persistentFlags.String("access-key", "", "Microsoft Genomics API access key")
-	rootCmd.MarkPersistentFlagRequired("access-key")
+	return ctx, cancel, nil
+}
+
+// newClientFromFlags returns a Microsoft Genomics API client using the service
+// configuration from the flags.
+func newClientFromFlags(flags *pflag.FlagSet) (internal.Client, error) {
+	config, err := internal.ServiceConfigFromFlags(flags)
+
+	if err != nil {
+		return internal.Client{}, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return internal.Client{}, err
+	}
+
+	options, err := internal.ClientOptionsFromFlags(flags)
+
+	if err != nil {
+		return internal.Client{}, err
+	}
+
+	return internal.NewClientWithOptions(config.BaseURL, config.AccessKey, options), nil
+}
+
+// addSecretFlags adds a flag for a secret along with the `-file` and
+// `-command` flags to read it from a file (or `-` for stdin) or the output of
+// a command, respectively. At most one of these flags can be given.
+func addSecretFlags(cmd *cobra.Command, flags *pflag.FlagSet, name string, usage string) {
+	names := internal.SecretFlagNames(name)
+
+	flags.String(names[0], "", usage)
+	flags.String(names[1], "", fmt.Sprintf("read %s from a file (- for stdin)", name))
+	flags.String(names[2], "", fmt.Sprintf("read %s from the stdout of a command", name))
+
+	for _, n := range names {
+		flags.SetAnnotation(n, internal.SecretSourceAnnotation, []string{name})
+	}
+
+	cmd.MarkFlagsMutuallyExclusive(names...)
+}
+
+// applyFlagSources fills in flags not given on the command line, first from
+// the submit spec (`--from-file`), if any, then from their bound environment
+// variables, and finally from the selected configuration profile. The base
+// URL and region are then resolved against each other by the precedence of
+// their sources (see `internal.ApplyRegion`), and logging and tracing are set
+// up (see `setUpLogging` and `setUpTracing`).
+//
+// This runs before cobra checks flag groups, so mutually exclusive flags are
+// checked across all sources.
+func applyFlagSources(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	if err := checkOutputFlags(flags); err != nil {
+		return err
+	}
+
+	sources := internal.FlagSources{}
+	sources.Record(flags)
+
+	if flags.Lookup("from-file") != nil {
+		path, err := flags.GetString("from-file")
+
+		if err != nil {
+			return err
+		}
+
+		if len(path) > 0 {
+			spec, err := internal.LoadSubmitSpec(path)
+
+			if err != nil {
+				return err
+			}
+
+			if err := internal.ApplySubmitSpec(flags, spec); err != nil {
+				return err
+			}
+		}
+	}
+
+	sources.Record(flags)
+
+	if err := internal.ApplyEnv(flags); err != nil {
+		return err
+	}
+
+	sources.Record(flags)
+
+	path, err := flags.GetString("config")
+
+	if err != nil {
+		return err
+	}
+
+	config, err := internal.LoadConfig(path)
+
+	if err != nil {
+		return err
+	}
+
+	name, err := flags.GetString("profile")
+
+	if err != nil {
+		return err
+	}
+
+	profile, err := config.Profile(name)
+
+	if err != nil {
+		return err
+	}
+
+	if err := internal.ApplyProfile(flags, profile); err != nil {
+		return err
+	}
+
+	sources.Record(flags)
+
+	regions := internal.DefaultRegions().Merge(config.Regions)
+
+	if err := internal.ApplyRegion(flags, regions, &sources); err != nil {
+		return err
+	}
+
+	if err := setUpLogging(cmd); err != nil {
+		return err
+	}
+
+	return setUpTracing(cmd)
+}
+
+// annotateEnvVars appends the bound environment variable name to the usage of
+// every flag of the command and its subcommands, except command line only
+// flags.
+func annotateEnvVars(cmd *cobra.Command) {
+	annotate := func(flag *pflag.Flag) {
+		if _, ok := flag.Annotations[internal.CommandLineOnlyAnnotation]; ok {
+			return
+		}
+
+		flag.Usage = fmt.Sprintf("%s [$%s]", flag.Usage, internal.EnvVarName(flag.Name))
+	}
+
+	cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		if flag.Name != "version" {
+			annotate(flag)
+		}
+	})
+	cmd.PersistentFlags().VisitAll(annotate)
+
+	for _, subcommand := range cmd.Commands() {
+		annotateEnvVars(subcommand)
+	}
 }
@@ -1,9 +1,12 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"log/slog"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/stjudecloud/msgenctl/internal"
@@ -17,19 +20,69 @@ var statusCmd = &cobra.Command{
 }
 
 func init() {
+	flags := statusCmd.Flags()
+
+	addFilterFlags(statusCmd, "list")
+
+	flags.String("sort", internal.WorkflowSortKeyCreated, fmt.Sprintf("sort key: %s", strings.Join(internal.WorkflowSortKeyNames, ", ")))
+	flags.Bool("reverse", false, "sort in descending order")
+	flags.Int("limit", 0, "list only the first N workflows in the sort order (0 for no limit)")
+	flags.Int("last", 0, "list only the last N workflows in the sort order (0 for no limit)")
+	flags.Int("page-size", 0, "number of workflows to request at once (0 to request all at once)")
+	statusCmd.MarkFlagsMutuallyExclusive("limit", "last")
+
+	markCommandLineOnly(flags, "limit", "last")
+
+	addOutputFlags(statusCmd, internal.OutputFormatDetail, "")
 	rootCmd.AddCommand(statusCmd)
 }
 
 func status(cmd *cobra.Command, args []string) error {
-	config, err := internal.ServiceConfigFromFlags(cmd.Flags())
+	ctx, cancel, err := newContextFromFlags(cmd)
+
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	client, err := newClientFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	outputOptions, err := outputOptionsFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := internal.NewWorkflowWriter(outputOptions)
+
+	if err != nil {
+		return err
+	}
+
+	filter, err := internal.WorkflowFilterFromFlags(cmd.Flags(), time.Now())
 
 	if err != nil {
 		return err
 	}
 
-	client := internal.NewClient(config.BaseURL, config.AccessKey)
+	listOptions, err := internal.WorkflowListOptionsFromFlags(cmd.Flags())
+
+	if err != nil {
+		return err
+	}
+
+	listOptions.Filter = filter
 
 	if len(args) > 0 {
+		if !filter.IsZero() {
+			return errors.New("filters cannot be used with a workflow ID")
+		}
+
 		rawWorkflowID, err := strconv.Atoi(args[0])
 
 		if err != nil {
@@ -40,43 +93,22 @@ func status(cmd *cobra.Command, args []string) error {
 
 		slog.Info("status", "workflowID", workflowID)
 
-		workflow, err := internal.FetchWorkflow(client, workflowID)
+		workflow, err := internal.FetchWorkflowContext(ctx, client, workflowID)
 
 		if err != nil {
 			return err
 		}
 
-		printWorkflow(workflow)
+		return writer.WriteWorkflow(cmd.OutOrStdout(), workflow)
 	} else {
 		slog.Info("status", "workflowID", "*")
 
-		workflows, err := internal.FetchWorkflows(client)
+		workflows, err := internal.ListWorkflowsContext(ctx, client, listOptions)
 
 		if err != nil {
 			return err
 		}
 
-		printWorkflows(workflows)
+		return writer.WriteWorkflows(cmd.OutOrStdout(), workflows)
 	}
-
-	return nil
-}
-
-func printWorkflows(workflows []internal.Workflow) {
-	for _, workflow := range workflows {
-		printWorkflow(workflow)
-		fmt.Println()
-	}
-}
-
-func printWorkflow(workflow internal.Workflow) {
-	fmt.Printf("Workflow ID     : %v\n", workflow.ID)
-	fmt.Printf("Status          : %s (%d)\n", workflow.Status, workflow.Status)
-	fmt.Printf("Message         : %s\n", workflow.Message)
-	fmt.Printf("Process         : %v\n", workflow.Process)
-	fmt.Printf("Description     : %v\n", workflow.Description)
-	fmt.Printf("Created Date    : %v\n", workflow.CreatedDate)
-	fmt.Printf("End Date        : %v\n", workflow.EndDate)
-	fmt.Printf("Wall Clock Time : %v\n", workflow.Duration())
-	fmt.Printf("Bases Processed : %d\n", workflow.BasesProcessed)
 }
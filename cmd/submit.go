@@ -9,27 +9,57 @@ import (
 )
 
 var submitCmd = &cobra.Command{
-	Use:   "submit",
-	Short: "submits a new workflow",
-	RunE:  submit,
+	Use:         "submit",
+	Short:       "submits a new workflow",
+	RunE:        submit,
+	Annotations: map[string]string{logLevelAnnotation: internal.LogLevelInfo},
 }
 
 func init() {
-	flags := submitCmd.Flags()
+	addSubmitFlags(submitCmd)
+
+	submitCmd.Flags().Bool("emit-spec", false, "print the effective spec as YAML instead of submitting")
+	submitCmd.Flags().Int(
+		"submit-retries",
+		internal.DefaultClientOptions().SubmitRetryMax,
+		"maximum number of retries of a failed submission, each after checking that no workflow was created",
+	)
+	addOutputFlags(submitCmd, "", " (default prints only the workflow ID)")
+
+	rootCmd.AddCommand(submitCmd)
+}
+
+// addSubmitFlags adds the flags that describe a submission.
+func addSubmitFlags(cmd *cobra.Command) {
+	flags := cmd.Flags()
 
 	// process
 	flags.String("process-name", "", "process name")
 	flags.String("process-args", "", "process arguments")
 
 	// input
-	flags.String("input-storage-connection-string", "", "input Azure Storage connection string")
+	addSecretFlags(
+		cmd,
+		flags,
+		"input-storage-connection-string",
+		"input Azure Storage connection string",
+	)
+	flags.String("input-storage-auth", internal.StorageAuthModeSharedKey, storageAuthUsage("input"))
+	flags.String("input-storage-region", "", "input Azure Storage account region, checked against the service region")
 	flags.String("input-storage-container-name", "", "input Azure Storage container name")
 	flags.String("input-blob-name", "", "input blob name")
 
 	flags.String("description", "", "workflow description")
 
 	// output
-	flags.String("output-storage-connection-string", "", "output Azure Storage connection string")
+	addSecretFlags(
+		cmd,
+		flags,
+		"output-storage-connection-string",
+		"output Azure Storage connection string",
+	)
+	flags.String("output-storage-auth", internal.StorageAuthModeSharedKey, storageAuthUsage("output"))
+	flags.String("output-storage-region", "", "output Azure Storage account region, checked against the service region")
 	flags.String("output-storage-container-name", "", "output Azure Storage container name")
 	flags.String("output-basename", "", "output basename")
 	flags.Bool("output-overwrite", false, "overwrite outputs")
@@ -45,26 +75,87 @@ func init() {
 
 	flags.Bool("ignore-azure-region", false, "allow data and service to be in different regions")
 
-	rootCmd.AddCommand(submitCmd)
+	// spec
+	flags.String("from-file", "", "read the submission from a YAML or JSON spec file")
+}
+
+func storageAuthUsage(prefix string) string {
+	return fmt.Sprintf(
+		"%s Azure Storage auth mode (shared-key, client-secret, workload-identity, managed-identity, azure-cli)",
+		prefix,
+	)
 }
 
 func submit(cmd *cobra.Command, args []string) error {
-	config, err := internal.SubmitConfigFromFlags(cmd.Flags())
+	flags := cmd.Flags()
+
+	emitSpec, err := flags.GetBool("emit-spec")
+
+	if err != nil {
+		return err
+	}
+
+	if emitSpec {
+		spec, err := internal.SubmitSpecFromFlags(flags)
+
+		if err != nil {
+			return err
+		}
+
+		return internal.WriteSubmitSpec(cmd.OutOrStdout(), spec)
+	}
+
+	ctx, cancel, err := newContextFromFlags(cmd)
+
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	outputOptions, err := outputOptionsFromFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := internal.NewWorkflowWriter(outputOptions)
 
 	if err != nil {
 		return err
 	}
 
+	config, err := internal.ValidateSubmitConfigFromFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	if !config.IgnoreAzureRegion {
+		for _, warning := range internal.RegionMismatches(config) {
+			slog.Warn("region mismatch", "message", warning)
+		}
+	}
+
 	slog.Info("submit", "description", config.Description)
 
-	client := internal.NewClient(config.Service.BaseURL, config.Service.AccessKey)
-	workflow, err := internal.SubmitWorkflow(client, config)
+	options, err := internal.ClientOptionsFromFlags(flags)
 
 	if err != nil {
 		return err
 	}
 
-	fmt.Println(workflow.ID)
+	client := internal.NewClientWithOptions(config.Service.BaseURL, config.Service.AccessKey, options)
+	workflow, err := internal.SubmitWorkflowContext(ctx, client, config)
+
+	if err != nil {
+		return err
+	}
+
+	if outputOptions.IsZero() {
+		fmt.Fprintln(cmd.OutOrStdout(), workflow.ID)
+		return nil
+	}
 
-	return nil
+	return writer.WriteWorkflow(cmd.OutOrStdout(), workflow)
 }
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary",
+	Short: "summarizes workflow counts, bases processed, durations, and failures",
+	Args:  cobra.NoArgs,
+	RunE:  summary,
+}
+
+func init() {
+	flags := summaryCmd.Flags()
+
+	addFilterFlags(summaryCmd, "summarize")
+
+	flags.StringSlice("group-by", nil, "group by day, week, or month of the creation date and/or description (prefix)")
+	flags.String("prefix-delimiter", internal.DefaultPrefixDelimiter, "delimiter ending the description prefix for --group-by description")
+	flags.StringP("output", "o", internal.OutputFormatTable, "output format: table or json")
+
+	// The output formats differ from those of the other commands, which share
+	// the profile key and environment variable.
+	markCommandLineOnly(flags, "output")
+
+	rootCmd.AddCommand(summaryCmd)
+}
+
+func summary(cmd *cobra.Command, args []string) error {
+	flags := cmd.Flags()
+
+	ctx, cancel, err := newContextFromFlags(cmd)
+
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	client, err := newClientFromFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	filter, err := internal.WorkflowFilterFromFlags(flags, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	options, err := internal.SummaryOptionsFromFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	rawFormat, err := flags.GetString("output")
+
+	if err != nil {
+		return err
+	}
+
+	format, err := internal.ParseOutputFormat(rawFormat)
+
+	if err != nil {
+		return err
+	}
+
+	if format != internal.OutputFormatTable && format != internal.OutputFormatJSON {
+		return fmt.Errorf("invalid summary output format: %q", format)
+	}
+
+	slog.Info("summary", "options", options)
+
+	workflows, err := internal.ListWorkflowsContext(ctx, client, internal.WorkflowListOptions{Filter: filter})
+
+	if err != nil {
+		return err
+	}
+
+	return internal.WriteSummary(cmd.OutOrStdout(), format, internal.Summarize(workflows, options))
+}
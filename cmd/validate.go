@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/stjudecloud/msgenctl/internal"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "checks a submission for errors without submitting it",
+	Args:  cobra.NoArgs,
+	RunE:  validate,
+}
+
+func init() {
+	addSubmitFlags(validateCmd)
+	rootCmd.AddCommand(validateCmd)
+}
+
+func validate(cmd *cobra.Command, args []string) error {
+	if _, err := internal.ValidateSubmitConfigFromFlags(cmd.Flags()); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), "submission is valid")
+
+	return nil
+}
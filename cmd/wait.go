@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strconv"
@@ -12,16 +13,18 @@ import (
 )
 
 var waitCmd = &cobra.Command{
-	Use:   "wait <workflow-id>",
-	Short: "polls until the completion of a workflow",
-	Args:  cobra.ExactArgs(1),
-	RunE:  wait,
+	Use:         "wait <workflow-id>",
+	Short:       "polls until the completion of a workflow",
+	Args:        cobra.ExactArgs(1),
+	RunE:        wait,
+	Annotations: map[string]string{logLevelAnnotation: internal.LogLevelInfo},
 }
 
 func init() {
 	flags := waitCmd.Flags()
 
 	flags.Int("interval", 60, "poll interval in seconds")
+	addOutputFlags(waitCmd, "", " (default prints nothing)")
 
 	rootCmd.AddCommand(waitCmd)
 }
@@ -29,7 +32,15 @@ func init() {
 func wait(cmd *cobra.Command, args []string) error {
 	flags := cmd.Flags()
 
-	config, err := internal.ServiceConfigFromFlags(flags)
+	ctx, cancel, err := newContextFromFlags(cmd)
+
+	if err != nil {
+		return err
+	}
+
+	defer cancel()
+
+	client, err := newClientFromFlags(flags)
 
 	if err != nil {
 		return err
@@ -41,6 +52,18 @@ func wait(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	outputOptions, err := outputOptionsFromFlags(flags)
+
+	if err != nil {
+		return err
+	}
+
+	writer, err := internal.NewWorkflowWriter(outputOptions)
+
+	if err != nil {
+		return err
+	}
+
 	rawWorkflowID, err := strconv.Atoi(args[0])
 
 	if err != nil {
@@ -51,10 +74,8 @@ func wait(cmd *cobra.Command, args []string) error {
 
 	slog.Info("wait", "workflowID", workflowID)
 
-	client := internal.NewClient(config.BaseURL, config.AccessKey)
-
 	for {
-		workflow, err := internal.FetchWorkflow(client, workflowID)
+		workflow, err := internal.FetchWorkflowContext(ctx, client, workflowID)
 
 		if err != nil {
 			return err
@@ -62,13 +83,28 @@ func wait(cmd *cobra.Command, args []string) error {
 
 		slog.Info("wait", "workflowID", workflowID, "status", workflow.Status, "message", workflow.Message)
 
-		switch workflow.Status {
-		case internal.StatusSuccess:
+		if !workflow.Status.IsKnown() {
+			slog.Warn("wait: unknown status; still waiting", "workflowID", workflowID, "status", workflow.Status)
+		}
+
+		if workflow.Status.IsTerminal() {
+			if !outputOptions.IsZero() {
+				if err := writer.WriteWorkflow(cmd.OutOrStdout(), workflow); err != nil {
+					return err
+				}
+			}
+
+			if workflow.Status != internal.StatusSuccess {
+				return internal.NewUnsuccessfulWorkflowError(workflow)
+			}
+
 			return nil
-		case internal.StatusFailed, internal.StatusCancelled:
-			return fmt.Errorf("workflow unsuccessful: %d: %s", workflow.Status, workflow.Message)
-		default:
-			time.Sleep(interval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("wait: %w", context.Cause(ctx))
+		case <-time.After(interval):
 		}
 	}
 }
@@ -0,0 +1,381 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/go-cleanhttp"
+)
+
+const cassetteFileExt = ".json"
+
+// errNotRecorded is returned by `ReplayTransport` for a request without a
+// recorded response. It is not retried.
+var errNotRecorded = errors.New("no recorded response")
+
+// redactedHeaders are masked entirely in cassettes.
+var redactedHeaders = []string{"Authorization", "Ocp-Apim-Subscription-Key"}
+
+// volatileFilterPatterns match the parts of an OData `$filter` that differ
+// between runs, with their replacements for matching: times, e.g., from
+// `--since 1h` or the lookup of a submission since it started, and
+// submission tokens.
+var volatileFilterPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`), "<time>"},
+	{submissionTokenPattern, submissionTag("<token>")},
+}
+
+// Interaction is a request and its response, as stored in a cassette file.
+//
+// Secrets are redacted (see `Redact`), so a cassette can be shared, e.g., in
+// an issue.
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+	Duration   string           `json:"duration"`
+}
+
+type RecordedRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// key returns the method and request URI (path and query) the interaction
+// is replayed for (see `replayKey`).
+func (r *RecordedRequest) key() (string, error) {
+	u, err := url.Parse(r.URL)
+
+	if err != nil {
+		return "", err
+	}
+
+	return replayKey(r.Method, u), nil
+}
+
+// replayKey returns the method and request URI (path and query) a request is
+// matched by. The host is ignored, so a cassette can be replayed with any
+// base URL, and the volatile parts of the `$filter` are replaced (see
+// `volatileFilterPatterns`), so, e.g., `--since 1h` matches at any time.
+func replayKey(method string, u *url.URL) string {
+	query := u.Query()
+
+	if filter := query.Get("$filter"); len(filter) > 0 {
+		for _, volatile := range volatileFilterPatterns {
+			filter = volatile.pattern.ReplaceAllString(filter, volatile.replacement)
+		}
+
+		query.Set("$filter", filter)
+	}
+
+	normalized := url.URL{Path: u.Path, RawPath: u.RawPath, RawQuery: query.Encode()}
+
+	return method + " " + normalized.RequestURI()
+}
+
+// RecordingTransport is an `http.RoundTripper` that writes each request and
+// its response to a file in a cassette directory (see `Interaction`).
+//
+// Files are named by sequence number, e.g., `0001-get.json`, continuing
+// after any existing files, so they are replayed in the recorded order.
+type RecordingTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	mu  sync.Mutex
+	seq int
+}
+
+// NewRecordingTransport returns a transport that sends requests with the base
+// transport and records them in the given directory. If base is nil, a
+// default transport is used.
+func NewRecordingTransport(base http.RoundTripper, dir string) *RecordingTransport {
+	if base == nil {
+		base = cleanhttp.DefaultPooledTransport()
+	}
+
+	return &RecordingTransport{base: base, dir: dir}
+}
+
+func (t *RecordingTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	requestBody, err := readBody(request.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	outgoing := request
+
+	if request.Body != nil {
+		outgoing = request.Clone(request.Context())
+		outgoing.Body = io.NopCloser(bytes.NewReader(requestBody))
+	}
+
+	start := time.Now()
+	response, err := t.base.RoundTrip(outgoing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	duration := time.Since(start)
+
+	responseBody, err := readBody(response.Body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method: request.Method,
+			URL:    Redact(request.URL.String()),
+			Header: redactHeader(request.Header),
+			Body:   Redact(string(requestBody)),
+		},
+		Response: RecordedResponse{
+			StatusCode: response.StatusCode,
+			Header:     redactHeader(response.Header),
+			Body:       Redact(string(responseBody)),
+		},
+		RecordedAt: start.UTC(),
+		Duration:   duration.String(),
+	}
+
+	if err := t.write(&interaction); err != nil {
+		return nil, fmt.Errorf("could not record response: %w", err)
+	}
+
+	return response, nil
+}
+
+func (t *RecordingTransport) write(interaction *Interaction) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return err
+	}
+
+	if t.seq == 0 {
+		paths, err := cassetteFiles(t.dir)
+
+		if err != nil {
+			return err
+		}
+
+		t.seq = len(paths)
+	}
+
+	t.seq++
+
+	data, err := json.MarshalIndent(interaction, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%04d-%s%s", t.seq, strings.ToLower(interaction.Request.Method), cassetteFileExt)
+
+	return os.WriteFile(filepath.Join(t.dir, name), append(data, '\n'), 0o600)
+}
+
+// ReplayTransport is an `http.RoundTripper` that serves responses from a
+// cassette directory written by `RecordingTransport`, without a network.
+//
+// A request is matched by its method, path, and query (see `replayKey`).
+// Matching interactions are served in the recorded order, and once all have
+// been served, the last one is repeated, e.g., for `wait` polling a completed
+// workflow.
+type ReplayTransport struct {
+	mu           sync.Mutex
+	interactions map[string][]Interaction
+	served       map[string]int
+}
+
+// NewReplayTransport loads the cassette files in the given directory.
+func NewReplayTransport(dir string) (*ReplayTransport, error) {
+	paths, err := cassetteFiles(dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read cassette: %w", err)
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no recorded interactions in %s", dir)
+	}
+
+	transport := &ReplayTransport{
+		interactions: map[string][]Interaction{},
+		served:       map[string]int{},
+	}
+
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var interaction Interaction
+
+		if err := json.Unmarshal(data, &interaction); err != nil {
+			return nil, fmt.Errorf("invalid cassette file: %s: %w", path, err)
+		}
+
+		key, err := interaction.Request.key()
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid cassette file: %s: %w", path, err)
+		}
+
+		transport.interactions[key] = append(transport.interactions[key], interaction)
+	}
+
+	return transport, nil
+}
+
+// CassetteBaseURL returns the scheme and host of the first recorded request
+// in the cassette directory, i.e., the base URL to replay it with when none
+// is given.
+func CassetteBaseURL(dir string) (string, error) {
+	paths, err := cassetteFiles(dir)
+
+	if err != nil {
+		return "", fmt.Errorf("could not read cassette: %w", err)
+	}
+
+	if len(paths) == 0 {
+		return "", fmt.Errorf("no recorded interactions in %s", dir)
+	}
+
+	data, err := os.ReadFile(paths[0])
+
+	if err != nil {
+		return "", err
+	}
+
+	var interaction Interaction
+
+	if err := json.Unmarshal(data, &interaction); err != nil {
+		return "", fmt.Errorf("invalid cassette file: %s: %w", paths[0], err)
+	}
+
+	u, err := url.Parse(interaction.Request.URL)
+
+	if err != nil {
+		return "", fmt.Errorf("invalid cassette file: %s: %w", paths[0], err)
+	}
+
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host}).String(), nil
+}
+
+func (t *ReplayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if request.Body != nil {
+		request.Body.Close()
+	}
+
+	key := replayKey(request.Method, request.URL)
+
+	t.mu.Lock()
+
+	interactions := t.interactions[key]
+
+	if len(interactions) == 0 {
+		t.mu.Unlock()
+		return nil, fmt.Errorf("%w for %s", errNotRecorded, key)
+	}
+
+	i := min(t.served[key], len(interactions)-1)
+	t.served[key]++
+
+	t.mu.Unlock()
+
+	recorded := interactions[i].Response
+
+	response := &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}
+
+	if response.Header == nil {
+		response.Header = http.Header{}
+	}
+
+	return response, nil
+}
+
+// cassetteFiles returns the sorted paths of the cassette files in the
+// directory. A missing directory has no files.
+func cassetteFiles(dir string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+cassetteFileExt))
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+// readBody reads and closes the body, if any.
+func readBody(body io.ReadCloser) ([]byte, error) {
+	if body == nil {
+		return nil, nil
+	}
+
+	defer body.Close()
+
+	return io.ReadAll(body)
+}
+
+func redactHeader(header http.Header) http.Header {
+	redacted := http.Header{}
+
+	for key, values := range header {
+		for _, value := range values {
+			redacted.Add(key, Redact(value))
+		}
+	}
+
+	for _, key := range redactedHeaders {
+		if len(redacted.Values(key)) > 0 {
+			redacted.Set(key, secretMask)
+		}
+	}
+
+	return redacted
+}
@@ -0,0 +1,279 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cassette")
+
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+
+		switch {
+		case r.Method == http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+
+			if !strings.Contains(string(body), "sig=") {
+				t.Errorf("expected the request body to be sent, got %q", body)
+			}
+
+			rw.Write([]byte(`{"Id":1,"Status":1000}`))
+		case n == 2:
+			rw.Write([]byte(`{"Id":1,"Status":20000}`))
+		default:
+			rw.Write([]byte(`{"Id":1,"Status":10000}`))
+		}
+	}))
+
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.APITransport = NewRecordingTransport(nil, dir)
+	client := NewClientWithOptions(server.URL, testAccessKey, options)
+
+	ctx := context.Background()
+
+	response, err := client.PostContext(ctx, "/api/workflows", map[string]string{
+		"sas": "sv=2021-06-08&sig=" + testSASSignature,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+
+	recorded := []string{}
+
+	for range 2 {
+		workflow, err := FetchWorkflowContext(ctx, client, 1)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		recorded = append(recorded, workflow.Status.String())
+	}
+
+	paths, err := cassetteFiles(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names := []string{}
+
+	for _, path := range paths {
+		names = append(names, filepath.Base(path))
+
+		data, err := os.ReadFile(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assertNoSecrets(t, string(data))
+	}
+
+	if diff := cmp.Diff(names, []string{"0001-post.json", "0002-get.json", "0003-get.json"}); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	replayTransport, err := NewReplayTransport(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options = DefaultClientOptions()
+	options.APITransport = replayTransport
+	client = NewClientWithOptions("http://msgenctl.invalid", testAccessKey, options)
+
+	replayed := []string{}
+
+	for range 3 {
+		workflow, err := FetchWorkflowContext(ctx, client, 1)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		replayed = append(replayed, workflow.Status.String())
+	}
+
+	// The last recorded response is repeated.
+	expected := append(recorded, recorded[len(recorded)-1])
+
+	if diff := cmp.Diff(replayed, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if _, err := client.GetContext(ctx, "/api/workflows/2"); err == nil {
+		t.Error("expected error for a request that was not recorded")
+	}
+
+	if n := requests.Load(); n != 3 {
+		t.Errorf("expected 3 requests to the server, got %d", n)
+	}
+}
+
+func TestRecordingTransportContinuesSequence(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "0001-get.json"), []byte("{}"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client := http.Client{Transport: NewRecordingTransport(nil, dir)}
+	response, err := client.Get(server.URL)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+
+	if _, err := os.Stat(filepath.Join(dir, "0002-get.json")); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewReplayTransportWithEmptyDirectory(t *testing.T) {
+	if _, err := NewReplayTransport(t.TempDir()); err == nil {
+		t.Error("expected error for an empty cassette")
+	}
+}
+
+func TestServiceConfigFromFlagsWithReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	data := `{"request": {"method": "GET", "url": "https://eastus.example.com/api/workflows/1"}, "response": {"statusCode": 200}}`
+
+	if err := os.WriteFile(filepath.Join(dir, "0001-get.json"), []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.String("base-url", "", "")
+	flags.String("access-key", "", "")
+	flags.String("region", "", "")
+	flags.String("replay", "", "")
+
+	if err := flags.Parse([]string{"--replay", dir}); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ServiceConfigFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ServiceConfig{BaseURL: "https://eastus.example.com", Replay: true}
+
+	if diff := cmp.Diff(config, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if err := config.Validate(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+}
+
+func TestReplayWithSince(t *testing.T) {
+	dir := t.TempDir()
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.Query().Get("$filter"), "CreatedDate ge") {
+			t.Errorf("expected a CreatedDate filter, got %q", r.URL.RawQuery)
+		}
+
+		json.NewEncoder(rw).Encode([]Workflow{{ID: 1, Status: StatusSuccess, CreatedDate: time.Now()}})
+	}))
+
+	defer server.Close()
+
+	ctx := context.Background()
+
+	options := DefaultClientOptions()
+	options.APITransport = NewRecordingTransport(nil, dir)
+	client := NewClientWithOptions(server.URL, testAccessKey, options)
+
+	listOptions := WorkflowListOptions{Filter: WorkflowFilter{Since: time.Now().Add(-time.Hour)}}
+
+	if _, err := ListWorkflowsContext(ctx, client, listOptions); err != nil {
+		t.Fatal(err)
+	}
+
+	replayTransport, err := NewReplayTransport(dir)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	options = DefaultClientOptions()
+	options.APITransport = replayTransport
+	client = NewClientWithOptions("http://msgenctl.invalid", testAccessKey, options)
+
+	// `--since 1h` a minute later
+	listOptions.Filter.Since = listOptions.Filter.Since.Add(time.Minute)
+
+	workflows, err := ListWorkflowsContext(ctx, client, listOptions)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workflows) != 1 || workflows[0].ID != 1 {
+		t.Errorf("expected workflow 1, got %v", workflows)
+	}
+}
+
+func TestReplayKey(t *testing.T) {
+	test := func(t testing.TB, rawURL string, expected string) {
+		t.Helper()
+
+		u, err := url.Parse(rawURL)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := replayKey(http.MethodGet, u); actual != expected {
+			t.Errorf("%s: expected %q, got %q", rawURL, expected, actual)
+		}
+	}
+
+	test(t, "http://a.example.com/api/workflows/1", "GET /api/workflows/1")
+	test(t, "http://a.example.com", "GET /")
+	test(
+		t,
+		"http://a.example.com/api/workflows?$orderby=Id%20desc&$filter=CreatedDate%20ge%202024-01-01T00:00:00Z",
+		"GET /api/workflows?%24filter=CreatedDate+ge+%3Ctime%3E&%24orderby=Id+desc",
+	)
+	test(
+		t,
+		"http://b.example.com/api/workflows?$filter=contains(Description,'[msgenctl-submission:0123abcd]')%20and%20CreatedDate%20ge%202024-01-01T00:00:00.5%2B01:00",
+		"GET /api/workflows?%24filter=contains%28Description%2C%27%5Bmsgenctl-submission%3A%3Ctoken%3E%5D%27%29+and+CreatedDate+ge+%3Ctime%3E",
+	)
+}
@@ -2,43 +2,164 @@ package internal
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"net/http/httputil"
+	"slices"
 	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
-const httpClientTimeout = 20 * time.Second
+const (
+	defaultRequestTimeout = 20 * time.Second
+	defaultRetryMax       = 4
+	defaultRetryWaitMin   = 1 * time.Second
+	defaultRetryWaitMax   = 30 * time.Second
+	defaultSubmitRetryMax = 3
+	defaultRateLimitBurst = 1
+)
+
+// defaultRetryableStatusCodes are the response status codes that are retried
+// by default.
+var defaultRetryableStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
 
 type Client struct {
 	httpClient *retryablehttp.Client
 	baseURL    string
 	accessKey  string
+
+	// submitRetryMax is the maximum number of retries of a workflow
+	// submission. See `SubmitWorkflowContext`.
+	submitRetryMax       int
+	retryableStatusCodes []int
+
+	// transport is also used for Azure Storage and Microsoft Entra ID
+	// requests. If nil, their defaults are used.
+	transport http.RoundTripper
+}
+
+// ClientOptions configures a `Client`.
+type ClientOptions struct {
+	// RetryMax is the maximum number of retries of a failed request.
+	RetryMax int
+	// RetryWaitMin and RetryWaitMax bound the exponential backoff between
+	// retries. A `Retry-After` header on an HTTP 429 or 503 takes precedence.
+	RetryWaitMin time.Duration
+	RetryWaitMax time.Duration
+	// RetryableStatusCodes are the response status codes that are retried.
+	// Transport errors are always retried.
+	RetryableStatusCodes []int
+
+	// RequestTimeout limits each request attempt. Zero means no limit.
+	RequestTimeout time.Duration
+
+	// RateLimit is the maximum number of requests per second, including
+	// retries, across all goroutines using the client. Zero means no limit.
+	RateLimit float64
+	// RateLimitBurst is the maximum number of requests sent at once.
+	RateLimitBurst int
+
+	// SubmitRetryMax is the maximum number of retries of a workflow
+	// submission. POST requests are not retried by the HTTP client, as they
+	// are not idempotent.
+	SubmitRetryMax int
+
+	// Transport sends requests to the API, Azure Storage, and Microsoft Entra
+	// ID, e.g., from `NewTransport`. If nil, the default transports are used.
+	Transport http.RoundTripper
+	// APITransport, if set, sends requests to the API instead of Transport,
+	// e.g., a `RecordingTransport` or `ReplayTransport`.
+	APITransport http.RoundTripper
+}
+
+// DefaultClientOptions returns the options used by `NewClient`.
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		RetryMax:             defaultRetryMax,
+		RetryWaitMin:         defaultRetryWaitMin,
+		RetryWaitMax:         defaultRetryWaitMax,
+		RetryableStatusCodes: slices.Clone(defaultRetryableStatusCodes),
+		RequestTimeout:       defaultRequestTimeout,
+		RateLimitBurst:       defaultRateLimitBurst,
+		SubmitRetryMax:       defaultSubmitRetryMax,
+	}
 }
 
 func NewClient(baseURL string, accessKey string) Client {
+	return NewClientWithOptions(baseURL, accessKey, DefaultClientOptions())
+}
+
+func NewClientWithOptions(baseURL string, accessKey string, options ClientOptions) Client {
 	httpClient := retryablehttp.NewClient()
-	httpClient.HTTPClient.Timeout = httpClientTimeout
+	httpClient.HTTPClient.Timeout = options.RequestTimeout
 	httpClient.Logger = slog.Default()
+	httpClient.RetryMax = options.RetryMax
+	httpClient.RetryWaitMin = options.RetryWaitMin
+	httpClient.RetryWaitMax = options.RetryWaitMax
+	httpClient.CheckRetry = newRetryPolicy(options.RetryableStatusCodes)
+	// Return the last response after retries are exhausted so it can be
+	// reported as an `APIError`.
+	httpClient.ErrorHandler = retryablehttp.PassthroughErrorHandler
+	httpClient.RequestLogHook = recordResendCount
+
+	if options.Transport != nil {
+		httpClient.HTTPClient.Transport = options.Transport
+	}
+
+	if options.APITransport != nil {
+		httpClient.HTTPClient.Transport = options.APITransport
+	}
+
+	if options.RateLimit > 0 {
+		httpClient.HTTPClient.Transport = newRateLimitedTransport(
+			httpClient.HTTPClient.Transport,
+			options.RateLimit,
+			options.RateLimitBurst,
+		)
+	}
 
 	return Client{
-		httpClient: httpClient,
-		baseURL:    baseURL,
-		accessKey:  accessKey,
+		httpClient:           httpClient,
+		baseURL:              baseURL,
+		accessKey:            accessKey,
+		submitRetryMax:       options.SubmitRetryMax,
+		retryableStatusCodes: options.RetryableStatusCodes,
+		transport:            options.Transport,
 	}
 }
 
+// blobServiceClientOptions returns the options for Azure Storage clients used
+// along with the client.
+func (c *Client) blobServiceClientOptions() BlobServiceClientOptions {
+	return BlobServiceClientOptions{Transport: c.transport}
+}
+
 func (c *Client) Delete(endpoint string) (*http.Response, error) {
+	return c.DeleteContext(context.Background(), endpoint)
+}
+
+// DeleteContext is like `Delete` but cancels the request, including any
+// retries, when the context is done.
+func (c *Client) DeleteContext(ctx context.Context, endpoint string) (*http.Response, error) {
 	method := http.MethodDelete
 	url := c.buildURL(endpoint)
 
 	slog.Info("request", "method", method, "url", url)
 
-	request, err := retryablehttp.NewRequest(method, url, nil)
+	request, err := retryablehttp.NewRequestWithContext(ctx, method, url, nil)
 
 	if err != nil {
 		return nil, err
@@ -48,12 +169,18 @@ func (c *Client) Delete(endpoint string) (*http.Response, error) {
 }
 
 func (c *Client) Get(endpoint string) (*http.Response, error) {
+	return c.GetContext(context.Background(), endpoint)
+}
+
+// GetContext is like `Get` but cancels the request, including any retries,
+// when the context is done.
+func (c *Client) GetContext(ctx context.Context, endpoint string) (*http.Response, error) {
 	method := http.MethodGet
 	url := c.buildURL(endpoint)
 
 	slog.Info("request", "method", method, "url", url)
 
-	request, err := retryablehttp.NewRequest(method, url, nil)
+	request, err := retryablehttp.NewRequestWithContext(ctx, method, url, nil)
 
 	if err != nil {
 		return nil, err
@@ -63,6 +190,15 @@ func (c *Client) Get(endpoint string) (*http.Response, error) {
 }
 
 func (c *Client) Post(endpoint string, data interface{}) (*http.Response, error) {
+	return c.PostContext(context.Background(), endpoint, data)
+}
+
+// PostContext is like `Post` but cancels the request when the context is
+// done.
+//
+// Unlike other requests, POST requests are not retried, as a request that
+// timed out may have still reached the service.
+func (c *Client) PostContext(ctx context.Context, endpoint string, data interface{}) (*http.Response, error) {
 	method := http.MethodPost
 	url := c.buildURL(endpoint)
 
@@ -75,7 +211,12 @@ func (c *Client) Post(endpoint string, data interface{}) (*http.Response, error)
 		return nil, err
 	}
 
-	request, err := retryablehttp.NewRequest(method, url, bytes.NewBuffer(payload))
+	request, err := retryablehttp.NewRequestWithContext(
+		withoutRetries(ctx),
+		method,
+		url,
+		bytes.NewBuffer(payload),
+	)
 
 	if err != nil {
 		return nil, err
@@ -88,8 +229,28 @@ func (c *Client) buildURL(endpoint string) string {
 	return fmt.Sprintf("%s%s", c.baseURL, endpoint)
 }
 
-func (c *Client) do(request *retryablehttp.Request) (*http.Response, error) {
+// do sends the request. Any non-200 response is returned as an `*APIError`.
+//
+// The request, including its retries, is traced as a client span, and the
+// trace context is propagated in the request headers.
+func (c *Client) do(request *retryablehttp.Request) (_ *http.Response, err error) {
+	ctx, span := otel.Tracer(tracerName).Start(
+		request.Context(),
+		fmt.Sprintf("%s %s", request.Method, request.URL.Path),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("http.request.method", request.Method),
+			attribute.String("url.full", request.URL.String()),
+			attribute.String("server.address", request.URL.Hostname()),
+		),
+	)
+
+	defer func() { EndSpan(span, err) }()
+
+	request = request.WithContext(ctx)
+
 	addHeaders(&request.Header, c.accessKey)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(request.Header))
 
 	response, err := c.httpClient.Do(request)
 
@@ -97,22 +258,28 @@ func (c *Client) do(request *retryablehttp.Request) (*http.Response, error) {
 		return nil, err
 	}
 
+	span.SetAttributes(attribute.Int("http.response.status_code", response.StatusCode))
+
 	status := fmt.Sprintf("%s %s", response.Proto, response.Status)
 
 	if response.StatusCode == http.StatusOK {
 		slog.Info(status)
 		return response, nil
-	} else {
-		slog.Error(status)
+	}
 
-		res, err := httputil.DumpResponse(response, true)
+	slog.Error(status)
 
-		if err != nil {
-			return nil, err
-		}
+	defer response.Body.Close()
 
-		return nil, fmt.Errorf(string(res))
-	}
+	return nil, newAPIError(response)
+}
+
+// recordResendCount records the number of retries of the request on its
+// span (see `Client.do`).
+func recordResendCount(_ retryablehttp.Logger, request *http.Request, attempt int) {
+	trace.SpanFromContext(request.Context()).SetAttributes(
+		attribute.Int("http.request.resend_count", attempt),
+	)
 }
 
 func addHeaders(headers *http.Header, accessKey string) {
@@ -1,9 +1,13 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 )
 
 func TestClientBuildURL(t *testing.T) {
@@ -35,3 +39,26 @@ func TestAddHeaders(t *testing.T) {
 	test(t, headers, "User-Agent", fmt.Sprintf("msgenctl/%v", Version))
 	test(t, headers, "Ocp-Apim-Subscription-Key", accessKey)
 }
+
+func TestClientGetContextCancelsRetries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(server.URL, "secret")
+	start := time.Now()
+	_, err := client.GetContext(ctx, "/api/workflows")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("expected the retry wait to be canceled, took %v", elapsed)
+	}
+}
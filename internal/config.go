@@ -1,7 +1,11 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
 
 	"github.com/spf13/pflag"
 )
@@ -9,12 +13,61 @@ import (
 type ServiceConfig struct {
 	BaseURL   string
 	AccessKey string
+	Region    string
+
+	// Replay is set if responses are replayed from a cassette (`--replay`),
+	// which does not need an access key.
+	Replay bool
+}
+
+// Validate checks that the base URL is valid and the access key is set,
+// unless responses are replayed.
+//
+// All problems are reported as `ValidationErrors`.
+func (c *ServiceConfig) Validate() error {
+	return validateServiceConfig(*c).errOrNil()
+}
+
+// ValidateBaseURL checks that s is an absolute HTTP(S) URL.
+func ValidateBaseURL(s string) error {
+	if len(s) == 0 {
+		return errors.New("missing value")
+	}
+
+	u, err := url.Parse(s)
+
+	if err != nil {
+		return err
+	}
+
+	if (u.Scheme != "https" && u.Scheme != "http") || len(u.Host) == 0 {
+		return fmt.Errorf("expected an absolute http(s) URL, got %q", s)
+	}
+
+	return nil
 }
 
 type StorageConfig struct {
-	AccountName   string
-	AccountKey    string
-	ContainerName string
+	AccountName           string
+	AccountKey            string
+	SharedAccessSignature string
+	BlobEndpoint          string
+	Auth                  StorageAuthMode
+	Region                string
+	ContainerName         string
+}
+
+// LogValue masks the account key and SAS.
+func (c StorageConfig) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("accountName", c.AccountName),
+		slog.String("accountKey", maskNonEmpty(c.AccountKey)),
+		slog.String("sharedAccessSignature", maskNonEmpty(c.SharedAccessSignature)),
+		slog.String("blobEndpoint", c.BlobEndpoint),
+		slog.String("auth", string(c.Auth)),
+		slog.String("region", c.Region),
+		slog.String("containerName", c.ContainerName),
+	)
 }
 
 type InputConfig struct {
@@ -52,8 +105,14 @@ type SubmitConfig struct {
 	IgnoreAzureRegion bool
 }
 
+// SubmitConfigFromFlags builds the submit configuration from the flags.
+//
+// Invalid values, e.g., an unparsable connection string, are reported as
+// `ValidationErrors`, with the rest of the configuration still set. See
+// `ValidateSubmitConfigFromFlags` to also validate the configuration.
 func SubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
 	config := SubmitConfig{}
+	errs := ValidationErrors{}
 
 	serviceConfig, err := ServiceConfigFromFlags(flags)
 
@@ -63,7 +122,7 @@ func SubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
 
 	config.Service = serviceConfig
 
-	inputConfig, err := inputConfigFromFlags(flags)
+	inputConfig, err := inputConfigFromFlags(flags, &errs)
 
 	if err != nil {
 		return config, err
@@ -87,7 +146,7 @@ func SubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
 
 	config.Description = description
 
-	outputConfig, err := outputConfigFromFlags(flags)
+	outputConfig, err := outputConfigFromFlags(flags, &errs)
 
 	if err != nil {
 		return config, err
@@ -95,7 +154,7 @@ func SubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
 
 	config.Output = outputConfig
 
-	optionalArgsConfig, err := optionalArgsConfigFromFlags(flags)
+	optionalArgsConfig, err := optionalArgsConfigFromFlags(flags, &errs)
 
 	if err != nil {
 		return config, err
@@ -111,7 +170,7 @@ func SubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
 
 	config.IgnoreAzureRegion = ignoreAzureRegion
 
-	return config, nil
+	return config, errs.errOrNil()
 }
 
 func ServiceConfigFromFlags(flags *pflag.FlagSet) (ServiceConfig, error) {
@@ -125,7 +184,7 @@ func ServiceConfigFromFlags(flags *pflag.FlagSet) (ServiceConfig, error) {
 
 	config.BaseURL = baseURL
 
-	accessKey, err := flags.GetString("access-key")
+	accessKey, err := ReadSecret(flags, "access-key")
 
 	if err != nil {
 		return config, err
@@ -133,13 +192,187 @@ func ServiceConfigFromFlags(flags *pflag.FlagSet) (ServiceConfig, error) {
 
 	config.AccessKey = accessKey
 
+	region, err := flags.GetString("region")
+
+	if err != nil {
+		return config, err
+	}
+
+	config.Region = region
+
+	if dir := lookupString(flags, "replay"); len(dir) > 0 {
+		config.Replay = true
+
+		if len(config.BaseURL) == 0 {
+			baseURL, err := CassetteBaseURL(dir)
+
+			if err != nil {
+				return config, err
+			}
+
+			config.BaseURL = baseURL
+		}
+	}
+
 	return config, nil
 }
 
-func inputConfigFromFlags(flags *pflag.FlagSet) (InputConfig, error) {
+// ClientOptionsFromFlags returns the client options from the flags. Options
+// without a flag keep their default.
+func ClientOptionsFromFlags(flags *pflag.FlagSet) (ClientOptions, error) {
+	options := DefaultClientOptions()
+
+	ints := map[string]*int{
+		"max-retries":      &options.RetryMax,
+		"submit-retries":   &options.SubmitRetryMax,
+		"rate-limit-burst": &options.RateLimitBurst,
+	}
+
+	for name, option := range ints {
+		if flags.Lookup(name) == nil {
+			continue
+		}
+
+		value, err := flags.GetInt(name)
+
+		if err != nil {
+			return options, err
+		}
+
+		if value < 0 {
+			return options, fmt.Errorf("invalid %s: %d: must be non-negative", name, value)
+		}
+
+		*option = value
+	}
+
+	durations := map[string]*time.Duration{
+		"retry-wait-min":  &options.RetryWaitMin,
+		"retry-wait-max":  &options.RetryWaitMax,
+		"request-timeout": &options.RequestTimeout,
+	}
+
+	for name, option := range durations {
+		if flags.Lookup(name) == nil {
+			continue
+		}
+
+		value, err := flags.GetDuration(name)
+
+		if err != nil {
+			return options, err
+		}
+
+		if value < 0 {
+			return options, fmt.Errorf("invalid %s: %v: must be non-negative", name, value)
+		}
+
+		*option = value
+	}
+
+	if options.RetryWaitMin > options.RetryWaitMax {
+		return options, fmt.Errorf(
+			"invalid retry-wait-min: %v: must be at most retry-wait-max (%v)",
+			options.RetryWaitMin,
+			options.RetryWaitMax,
+		)
+	}
+
+	if flags.Lookup("retry-status-codes") != nil {
+		statusCodes, err := flags.GetIntSlice("retry-status-codes")
+
+		if err != nil {
+			return options, err
+		}
+
+		for _, statusCode := range statusCodes {
+			if statusCode < 100 || statusCode > 599 {
+				return options, fmt.Errorf("invalid retry-status-codes: %d: must be an HTTP status code", statusCode)
+			}
+		}
+
+		options.RetryableStatusCodes = statusCodes
+	}
+
+	if flags.Lookup("rate-limit") != nil {
+		rateLimit, err := flags.GetFloat64("rate-limit")
+
+		if err != nil {
+			return options, err
+		}
+
+		if rateLimit < 0 {
+			return options, fmt.Errorf("invalid rate-limit: %v: must be non-negative", rateLimit)
+		}
+
+		options.RateLimit = rateLimit
+	}
+
+	transportOptions, err := transportOptionsFromFlags(flags)
+
+	if err != nil {
+		return options, err
+	}
+
+	if !transportOptions.IsZero() {
+		transport, err := NewTransport(transportOptions)
+
+		if err != nil {
+			return options, err
+		}
+
+		options.Transport = transport
+	}
+
+	if dir := lookupString(flags, "replay"); len(dir) > 0 {
+		transport, err := NewReplayTransport(dir)
+
+		if err != nil {
+			return options, err
+		}
+
+		options.APITransport = transport
+	} else if dir := lookupString(flags, "record"); len(dir) > 0 {
+		options.APITransport = NewRecordingTransport(options.Transport, dir)
+	}
+
+	return options, nil
+}
+
+func transportOptionsFromFlags(flags *pflag.FlagSet) (TransportOptions, error) {
+	options := TransportOptions{}
+
+	if flags.Lookup("proxy") != nil {
+		proxyURL, err := ReadSecret(flags, "proxy")
+
+		if err != nil {
+			return options, err
+		}
+
+		options.ProxyURL = proxyURL
+	}
+
+	options.CABundle = lookupString(flags, "ca-bundle")
+	options.ClientCert = lookupString(flags, "client-cert")
+	options.ClientKey = lookupString(flags, "client-key")
+
+	if flags.Lookup("insecure-skip-verify") != nil {
+		insecureSkipVerify, err := flags.GetBool("insecure-skip-verify")
+
+		if err != nil {
+			return options, err
+		}
+
+		options.InsecureSkipVerify = insecureSkipVerify
+	}
+
+	return options, nil
+}
+
+func inputConfigFromFlags(flags *pflag.FlagSet, errs *ValidationErrors) (InputConfig, error) {
 	config := InputConfig{}
 
-	storageConfig, err := storageConfigFromFlags(flags, "input")
+	storageConfig, err := storageConfigFromFlags(flags, "input", errs)
 
 	if err != nil {
 		return config, err
@@ -180,10 +413,10 @@ func processConfigFromFlags(flags *pflag.FlagSet) (ProcessConfig, error) {
 	return config, nil
 }
 
-func outputConfigFromFlags(flags *pflag.FlagSet) (OutputConfig, error) {
+func outputConfigFromFlags(flags *pflag.FlagSet, errs *ValidationErrors) (OutputConfig, error) {
 	config := OutputConfig{}
 
-	storageConfig, err := storageConfigFromFlags(flags, "output")
+	storageConfig, err := storageConfigFromFlags(flags, "output", errs)
 
 	if err != nil {
 		return config, err
@@ -218,26 +451,55 @@ func outputConfigFromFlags(flags *pflag.FlagSet) (OutputConfig, error) {
 	return config, nil
 }
 
-func storageConfigFromFlags(flags *pflag.FlagSet, prefix string) (StorageConfig, error) {
+func storageConfigFromFlags(flags *pflag.FlagSet, prefix string, errs *ValidationErrors) (StorageConfig, error) {
 	var key string
 
 	config := StorageConfig{}
 
 	key = fmt.Sprintf("%v-storage-connection-string", prefix)
-	rawConnectionString, err := flags.GetString(key)
+	rawConnectionString, err := ReadSecret(flags, key)
+
+	if err != nil {
+		return config, err
+	}
+
+	// A missing connection string is reported by `ValidateSubmitConfig`.
+	if len(rawConnectionString) > 0 {
+		connectionString, err := ParseConnectionString(rawConnectionString)
+
+		if err != nil {
+			errs.add(key, "%v", err)
+		}
+
+		config.AccountName = connectionString.AccountName
+		config.AccountKey = connectionString.AccountKey
+		config.SharedAccessSignature = connectionString.SharedAccessSignature
+		config.BlobEndpoint = connectionString.BlobServiceURL()
+	}
+
+	key = fmt.Sprintf("%v-storage-auth", prefix)
+	rawAuth, err := flags.GetString(key)
 
 	if err != nil {
 		return config, err
 	}
 
-	connectionString, err := ParseConnectionString(rawConnectionString)
+	// An invalid value is kept to be reported again by
+	// `ValidateSubmitConfig`.
+	config.Auth = StorageAuthMode(rawAuth)
+
+	if _, err := ParseStorageAuthMode(rawAuth); err != nil {
+		errs.add(key, "%v", err)
+	}
+
+	key = fmt.Sprintf("%v-storage-region", prefix)
+	region, err := flags.GetString(key)
 
 	if err != nil {
 		return config, err
 	}
 
-	config.AccountName = connectionString.AccountName
-	config.AccountKey = connectionString.AccountKey
+	config.Region = region
 
 	key = fmt.Sprintf("%v-storage-container-name", prefix)
 	containerName, err := flags.GetString(key)
@@ -251,7 +513,7 @@ func storageConfigFromFlags(flags *pflag.FlagSet, prefix string) (StorageConfig,
 	return config, nil
 }
 
-func optionalArgsConfigFromFlags(flags *pflag.FlagSet) (OptionalArgsConfig, error) {
+func optionalArgsConfigFromFlags(flags *pflag.FlagSet, errs *ValidationErrors) (OptionalArgsConfig, error) {
 	config := OptionalArgsConfig{}
 
 	emitRefConfidence, err := flags.GetString("emit-ref-confidence")
@@ -260,14 +522,14 @@ func optionalArgsConfigFromFlags(flags *pflag.FlagSet) (OptionalArgsConfig, erro
 		return config, err
 	}
 
-	referenceConfidenceMode, err := ParseReferenceConfidenceMode(emitRefConfidence)
+	// An invalid value is kept to be reported again by
+	// `ValidateSubmitConfig`.
+	config.EmitRefConfidence = ReferenceConfidenceMode(emitRefConfidence)
 
-	if err != nil {
-		return config, err
+	if _, err := ParseReferenceConfidenceMode(emitRefConfidence); err != nil {
+		errs.add("emit-ref-confidence", "%v", err)
 	}
 
-	config.EmitRefConfidence = referenceConfidenceMode
-
 	bgzipOutput, err := flags.GetBool("bgzip-output")
 
 	if err != nil {
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/spf13/pflag"
@@ -11,13 +12,18 @@ func TestSubmitConfigFromFlags(t *testing.T) {
 	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	baseURL := flags.String("base-url", "", "")
 	accessKey := flags.String("access-key", "", "")
+	region := flags.String("region", "", "")
 	processName := flags.String("process-name", "", "")
 	processArgs := flags.String("process-args", "", "")
 	flags.String("input-storage-connection-string", "", "")
+	flags.String("input-storage-auth", StorageAuthModeSharedKey, "")
+	inputStorageRegion := flags.String("input-storage-region", "", "")
 	inputStorageContainerName := flags.String("input-storage-container-name", "", "")
 	inputBlobName := flags.String("input-blob-name", "", "")
 	description := flags.String("description", "", "")
 	flags.String("output-storage-connection-string", "", "")
+	flags.String("output-storage-auth", StorageAuthModeSharedKey, "")
+	outputStorageRegion := flags.String("output-storage-region", "", "")
 	outputStorageContainerName := flags.String("output-storage-container-name", "", "")
 	outputBasename := flags.String("output-basename", "", "")
 	overwrite := flags.Bool("output-overwrite", false, "")
@@ -29,13 +35,17 @@ func TestSubmitConfigFromFlags(t *testing.T) {
 	args := []string{
 		"--base-url", "https://example.com",
 		"--access-key", "secret",
+		"--region", "eastus",
 		"--process-name", "snapgatk-20190409_1",
 		"--process-args", "R=hg38m1x",
 		"--input-storage-connection-string", "AccountName=input;AccountKey=input-secret;",
+		"--input-storage-auth", "azure-cli",
+		"--input-storage-region", "eastus",
 		"--input-storage-container-name", "data",
 		"--input-blob-name", "sample.bam",
 		"--description", "sample run",
 		"--output-storage-connection-string", "AccountName=output;AccountKey=output-secret;",
+		"--output-storage-region", "westus2",
 		"--output-storage-container-name", "results",
 		"--output-basename", "sample",
 		"--output-overwrite",
@@ -59,11 +69,15 @@ func TestSubmitConfigFromFlags(t *testing.T) {
 		Service: ServiceConfig{
 			BaseURL:   *baseURL,
 			AccessKey: *accessKey,
+			Region:    *region,
 		},
 		Input: InputConfig{
 			Storage: StorageConfig{
 				AccountName:   "input",
 				AccountKey:    "input-secret",
+				BlobEndpoint:  "https://input.blob.core.windows.net",
+				Auth:          StorageAuthModeAzureCLI,
+				Region:        *inputStorageRegion,
 				ContainerName: *inputStorageContainerName,
 			},
 			BlobName: *inputBlobName,
@@ -77,6 +91,9 @@ func TestSubmitConfigFromFlags(t *testing.T) {
 			Storage: StorageConfig{
 				AccountName:   "output",
 				AccountKey:    "output-secret",
+				BlobEndpoint:  "https://output.blob.core.windows.net",
+				Auth:          StorageAuthModeSharedKey,
+				Region:        *outputStorageRegion,
 				ContainerName: *outputStorageContainerName,
 			},
 			Basename:   *outputBasename,
@@ -99,10 +116,12 @@ func TestServiceConfigFromFlags(t *testing.T) {
 	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
 	baseURL := flags.String("base-url", "", "")
 	accessKey := flags.String("access-key", "", "")
+	region := flags.String("region", "", "")
 
 	args := []string{
 		"--base-url", "https://example.com",
 		"--access-key", "secret",
+		"--region", "eastus",
 	}
 
 	if err := flags.Parse(args); err != nil {
@@ -118,9 +137,138 @@ func TestServiceConfigFromFlags(t *testing.T) {
 	expected := ServiceConfig{
 		BaseURL:   *baseURL,
 		AccessKey: *accessKey,
+		Region:    *region,
 	}
 
 	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
 		t.Errorf("config mismatch (-actual, +expected):\n%s", diff)
 	}
 }
+
+func TestServiceConfigValidate(t *testing.T) {
+	valid := ServiceConfig{
+		BaseURL:   "https://example.com",
+		AccessKey: "secret",
+	}
+
+	if err := valid.Validate(); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	invalid := ServiceConfig{}
+
+	if err := invalid.Validate(); err == nil {
+		t.Error("expected failure: empty config")
+	}
+}
+
+func TestValidateBaseURL(t *testing.T) {
+	test := func(t testing.TB, s string, ok bool) {
+		t.Helper()
+
+		err := ValidateBaseURL(s)
+
+		if ok && err != nil {
+			t.Errorf("unexpected failure: s = %q: %v", s, err)
+		} else if !ok && err == nil {
+			t.Errorf("expected failure: s = %q", s)
+		}
+	}
+
+	test(t, "https://eastus.api.microsoftgenomics.net", true)
+	test(t, "http://localhost:8080", true)
+	test(t, "", false)
+	test(t, "eastus.api.microsoftgenomics.net", false)
+	test(t, "ftp://example.com", false)
+}
+
+func newClientOptionsFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	options := DefaultClientOptions()
+
+	flags.Int("max-retries", options.RetryMax, "")
+	flags.Duration("retry-wait-min", options.RetryWaitMin, "")
+	flags.Duration("retry-wait-max", options.RetryWaitMax, "")
+	flags.IntSlice("retry-status-codes", options.RetryableStatusCodes, "")
+	flags.Duration("request-timeout", options.RequestTimeout, "")
+	flags.Float64("rate-limit", options.RateLimit, "")
+	flags.Int("rate-limit-burst", options.RateLimitBurst, "")
+	flags.Int("submit-retries", options.SubmitRetryMax, "")
+
+	return flags
+}
+
+func TestClientOptionsFromFlags(t *testing.T) {
+	flags := newClientOptionsFlagSet()
+
+	args := []string{
+		"--max-retries", "2",
+		"--retry-wait-min", "500ms",
+		"--retry-wait-max", "10s",
+		"--retry-status-codes", "429,503",
+		"--request-timeout", "1m",
+		"--rate-limit", "2.5",
+		"--rate-limit-burst", "4",
+		"--submit-retries", "0",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := ClientOptionsFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ClientOptions{
+		RetryMax:             2,
+		RetryWaitMin:         500 * time.Millisecond,
+		RetryWaitMax:         10 * time.Second,
+		RetryableStatusCodes: []int{429, 503},
+		RequestTimeout:       time.Minute,
+		RateLimit:            2.5,
+		RateLimitBurst:       4,
+		SubmitRetryMax:       0,
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("options mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestClientOptionsFromFlagsWithoutFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+
+	actual, err := ClientOptionsFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, DefaultClientOptions()); len(diff) != 0 {
+		t.Errorf("options mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestClientOptionsFromFlagsWithInvalidValues(t *testing.T) {
+	test := func(t testing.TB, args []string) {
+		t.Helper()
+
+		flags := newClientOptionsFlagSet()
+
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := ClientOptionsFromFlags(flags); err == nil {
+			t.Errorf("expected failure: args = %v", args)
+		}
+	}
+
+	test(t, []string{"--max-retries", "-1"})
+	test(t, []string{"--retry-wait-min", "1m", "--retry-wait-max", "1s"})
+	test(t, []string{"--retry-status-codes", "42"})
+	test(t, []string{"--rate-limit", "-1"})
+}
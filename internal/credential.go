@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+)
+
+// StorageAuthMode is how msgenctl authenticates to Azure Storage to sign a
+// SAS.
+type StorageAuthMode string
+
+const (
+	// StorageAuthModeSharedKey signs with the account key, or uses the SAS,
+	// from the connection string.
+	StorageAuthModeSharedKey = "shared-key"
+
+	// The following modes use a Microsoft Entra ID token to request a user
+	// delegation key, which signs a user delegation SAS.
+
+	// StorageAuthModeClientSecret uses a service principal secret from
+	// `AZURE_TENANT_ID`, `AZURE_CLIENT_ID`, and `AZURE_CLIENT_SECRET`.
+	StorageAuthModeClientSecret = "client-secret"
+	// StorageAuthModeWorkloadIdentity uses a federated token from
+	// `AZURE_TENANT_ID`, `AZURE_CLIENT_ID`, and `AZURE_FEDERATED_TOKEN_FILE`,
+	// e.g., in Kubernetes.
+	StorageAuthModeWorkloadIdentity = "workload-identity"
+	// StorageAuthModeManagedIdentity uses the managed identity of the host.
+	// `AZURE_CLIENT_ID` selects a user-assigned identity.
+	StorageAuthModeManagedIdentity = "managed-identity"
+	// StorageAuthModeAzureCLI uses the account logged in with `az login`.
+	StorageAuthModeAzureCLI = "azure-cli"
+)
+
+func ParseStorageAuthMode(s string) (StorageAuthMode, error) {
+	switch s {
+	case "shared-key":
+		return StorageAuthModeSharedKey, nil
+	case "client-secret":
+		return StorageAuthModeClientSecret, nil
+	case "workload-identity":
+		return StorageAuthModeWorkloadIdentity, nil
+	case "managed-identity":
+		return StorageAuthModeManagedIdentity, nil
+	case "azure-cli":
+		return StorageAuthModeAzureCLI, nil
+	default:
+		return "", fmt.Errorf("invalid storage auth mode: %q", s)
+	}
+}
+
+// UsesTokenCredential returns whether the mode authenticates with Microsoft
+// Entra ID rather than an account key.
+func (m StorageAuthMode) UsesTokenCredential() bool {
+	return len(m) > 0 && m != StorageAuthModeSharedKey
+}
+
+// NewTokenCredential returns a Microsoft Entra ID credential for the mode.
+func NewTokenCredential(mode StorageAuthMode) (azcore.TokenCredential, error) {
+	return newTokenCredential(mode, azcore.ClientOptions{})
+}
+
+// newTokenCredential is like `NewTokenCredential` but sends token requests
+// with the given client options. The Azure CLI credential runs `az` instead.
+func newTokenCredential(mode StorageAuthMode, clientOptions azcore.ClientOptions) (azcore.TokenCredential, error) {
+	switch mode {
+	case StorageAuthModeClientSecret:
+		return azidentity.NewClientSecretCredential(
+			os.Getenv("AZURE_TENANT_ID"),
+			os.Getenv("AZURE_CLIENT_ID"),
+			os.Getenv("AZURE_CLIENT_SECRET"),
+			&azidentity.ClientSecretCredentialOptions{ClientOptions: clientOptions},
+		)
+	case StorageAuthModeWorkloadIdentity:
+		return azidentity.NewWorkloadIdentityCredential(
+			&azidentity.WorkloadIdentityCredentialOptions{ClientOptions: clientOptions},
+		)
+	case StorageAuthModeManagedIdentity:
+		options := azidentity.ManagedIdentityCredentialOptions{ClientOptions: clientOptions}
+
+		if clientID := os.Getenv("AZURE_CLIENT_ID"); len(clientID) > 0 {
+			options.ID = azidentity.ClientID(clientID)
+		}
+
+		return azidentity.NewManagedIdentityCredential(&options)
+	case StorageAuthModeAzureCLI:
+		return azidentity.NewAzureCLICredential(nil)
+	default:
+		return nil, fmt.Errorf("storage auth mode does not use a token credential: %q", mode)
+	}
+}
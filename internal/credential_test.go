@@ -0,0 +1,42 @@
+package internal
+
+import "testing"
+
+func TestParseStorageAuthMode(t *testing.T) {
+	test := func(t testing.TB, s string, expected StorageAuthMode) {
+		t.Helper()
+
+		if actual, err := ParseStorageAuthMode(s); err == nil {
+			if actual != expected {
+				t.Errorf("expected %v, got %v", expected, actual)
+			}
+		} else {
+			t.Errorf(`unexpected failure: s = %q`, s)
+		}
+	}
+
+	test(t, "shared-key", StorageAuthModeSharedKey)
+	test(t, "client-secret", StorageAuthModeClientSecret)
+	test(t, "workload-identity", StorageAuthModeWorkloadIdentity)
+	test(t, "managed-identity", StorageAuthModeManagedIdentity)
+	test(t, "azure-cli", StorageAuthModeAzureCLI)
+
+	if _, err := ParseStorageAuthMode("msgenctl"); err == nil {
+		t.Error(`expected failure: s = "msgenctl"`)
+	}
+}
+
+func TestStorageAuthModeUsesTokenCredential(t *testing.T) {
+	test := func(t testing.TB, mode StorageAuthMode, expected bool) {
+		t.Helper()
+
+		if actual := mode.UsesTokenCredential(); actual != expected {
+			t.Errorf("%q: expected %v, got %v", mode, expected, actual)
+		}
+	}
+
+	test(t, "", false)
+	test(t, StorageAuthModeSharedKey, false)
+	test(t, StorageAuthModeClientSecret, true)
+	test(t, StorageAuthModeAzureCLI, true)
+}
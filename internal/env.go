@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+const envVarPrefix = "MSGEN_"
+
+// EnvVarName returns the name of the environment variable bound to the given
+// flag, e.g., `MSGEN_INPUT_STORAGE_CONTAINER_NAME` for
+// `input-storage-container-name`.
+func EnvVarName(flagName string) string {
+	name := strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+	return envVarPrefix + name
+}
+
+// ApplyEnv sets each flag that was not explicitly given on the command line
+// to the value of its bound environment variable, if set and nonempty.
+func ApplyEnv(flags *pflag.FlagSet) error {
+	return applyFlagSource(flags, func(name string) (string, string, bool) {
+		key := EnvVarName(name)
+		value := os.Getenv(key)
+		ok := isEnvBindable(name) && len(value) > 0
+		return fmt.Sprintf("environment variable value for %s", key), value, ok
+	})
+}
+
+func isEnvBindable(flagName string) bool {
+	return flagName != "help" && flagName != "version"
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func TestEnvVarName(t *testing.T) {
+	test := func(t testing.TB, flagName string, expected string) {
+		t.Helper()
+
+		actual := EnvVarName(flagName)
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "base-url", "MSGEN_BASE_URL")
+	test(t, "access-key", "MSGEN_ACCESS_KEY")
+	test(t, "input-storage-container-name", "MSGEN_INPUT_STORAGE_CONTAINER_NAME")
+}
+
+func TestApplyEnv(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	baseURL := flags.String("base-url", "", "")
+	accessKey := flags.String("access-key", "", "")
+	overwrite := flags.Bool("output-overwrite", false, "")
+	description := flags.String("description", "default", "")
+	help := flags.Bool("help", false, "")
+
+	t.Setenv("MSGEN_BASE_URL", "https://example.com")
+	t.Setenv("MSGEN_ACCESS_KEY", "env-secret")
+	t.Setenv("MSGEN_OUTPUT_OVERWRITE", "true")
+	t.Setenv("MSGEN_DESCRIPTION", "")
+	t.Setenv("MSGEN_HELP", "true")
+
+	args := []string{
+		"--access-key", "flag-secret",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ApplyEnv(flags); err != nil {
+		t.Fatal(err)
+	}
+
+	if *baseURL != "https://example.com" {
+		t.Errorf("expected base-url from environment, got %q", *baseURL)
+	}
+
+	if *accessKey != "flag-secret" {
+		t.Errorf("expected access-key from flag, got %q", *accessKey)
+	}
+
+	if !*overwrite {
+		t.Error("expected output-overwrite from environment")
+	}
+
+	if *description != "default" {
+		t.Errorf("expected default description, got %q", *description)
+	}
+
+	if *help {
+		t.Error("expected help to not be bound to the environment")
+	}
+
+	if !flags.Changed("base-url") {
+		t.Error("expected base-url to be marked as changed")
+	}
+}
+
+func TestApplyEnvWithInvalidValue(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.Bool("output-overwrite", false, "")
+
+	t.Setenv("MSGEN_OUTPUT_OVERWRITE", "maybe")
+
+	if err := ApplyEnv(flags); err == nil {
+		t.Error(`expected failure: MSGEN_OUTPUT_OVERWRITE = "maybe"`)
+	}
+}
@@ -0,0 +1,142 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxAPIErrorBodySize limits how much of an error response body is read.
+const maxAPIErrorBodySize = 64 * 1024
+
+// APIError is an unsuccessful (non-200) response from the Microsoft Genomics
+// API.
+type APIError struct {
+	StatusCode int
+	Method     string
+	URL        string
+
+	// Code and Message are parsed from the JSON body, if present.
+	Code    string
+	Message string
+
+	// Body is the raw response body.
+	Body []byte
+
+	// RetryAfter is the delay requested by the `Retry-After` header, if any.
+	RetryAfter time.Duration
+}
+
+// apiErrorBody is the error body of the API. API Management and the service
+// use different shapes, e.g., `{"statusCode": 401, "message": "..."}`,
+// `{"Code": "...", "Message": "..."}`, and `{"error": {"code": "...",
+// "message": "..."}}`. Field names are matched case-insensitively.
+type apiErrorBody struct {
+	Code    string
+	Message string
+	Error   *struct {
+		Code    string
+		Message string
+	}
+}
+
+// newAPIError builds an error from the response. The response body is read
+// but not closed.
+func newAPIError(response *http.Response) *APIError {
+	err := &APIError{
+		StatusCode: response.StatusCode,
+		RetryAfter: parseRetryAfter(response.Header.Get("Retry-After"), time.Now()),
+	}
+
+	if response.Request != nil {
+		err.Method = response.Request.Method
+		err.URL = response.Request.URL.String()
+	}
+
+	body, readErr := io.ReadAll(io.LimitReader(response.Body, maxAPIErrorBodySize))
+
+	if readErr != nil {
+		return err
+	}
+
+	err.Body = body
+
+	payload := apiErrorBody{}
+
+	if json.Unmarshal(body, &payload) == nil {
+		err.Code = payload.Code
+		err.Message = payload.Message
+
+		if payload.Error != nil {
+			err.Code = payload.Error.Code
+			err.Message = payload.Error.Message
+		}
+	}
+
+	return err
+}
+
+// parseRetryAfter parses a `Retry-After` header value, either in seconds or
+// an HTTP date. Invalid values or dates in the past are zero.
+func parseRetryAfter(s string, now time.Time) time.Duration {
+	if len(s) == 0 {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(s); err == nil {
+		return time.Duration(max(seconds, 0)) * time.Second
+	}
+
+	if date, err := http.ParseTime(s); err == nil {
+		return max(date.Sub(now), 0)
+	}
+
+	return 0
+}
+
+// Error returns the request, status, and error message or body. Secrets are
+// masked (see `Redact`).
+func (e *APIError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s %s: %d %s", e.Method, e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+
+	if len(e.Code) > 0 {
+		fmt.Fprintf(&b, ": %s", e.Code)
+	}
+
+	if len(e.Message) > 0 {
+		fmt.Fprintf(&b, ": %s", e.Message)
+	} else if body := strings.TrimSpace(string(e.Body)); len(body) > 0 {
+		fmt.Fprintf(&b, ": %s", body)
+	}
+
+	return Redact(b.String())
+}
+
+// UnsuccessfulWorkflowError is a workflow that completed with a failed or
+// cancelled status.
+type UnsuccessfulWorkflowError struct {
+	ID          WorkflowID
+	Status      Status
+	FailureCode int
+	Message     string
+}
+
+// NewUnsuccessfulWorkflowError returns an error for the workflow.
+func NewUnsuccessfulWorkflowError(workflow Workflow) *UnsuccessfulWorkflowError {
+	return &UnsuccessfulWorkflowError{
+		ID:          workflow.ID,
+		Status:      workflow.Status,
+		FailureCode: workflow.FailureCode,
+		Message:     workflow.Message,
+	}
+}
+
+func (e *UnsuccessfulWorkflowError) Error() string {
+	return fmt.Sprintf("workflow %v unsuccessful: %s (%d): %s", e.ID, e.Status, e.Status, e.Message)
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientGetWithAPIError(t *testing.T) {
+	test := func(t testing.TB, statusCode int, body string, expectedCode string, expectedMessage string) {
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(statusCode)
+			rw.Write([]byte(body))
+		}))
+
+		defer server.Close()
+
+		client := NewClient(server.URL, "secret")
+		client.httpClient.RetryMax = 0
+
+		_, err := client.Get("/api/workflows/1")
+
+		var apiErr *APIError
+
+		if !errors.As(err, &apiErr) {
+			t.Fatalf("expected *APIError, got %T: %v", err, err)
+		}
+
+		if apiErr.StatusCode != statusCode {
+			t.Errorf("expected status code %d, got %d", statusCode, apiErr.StatusCode)
+		}
+
+		if apiErr.Method != http.MethodGet {
+			t.Errorf("expected method %s, got %s", http.MethodGet, apiErr.Method)
+		}
+
+		if expectedURL := server.URL + "/api/workflows/1"; apiErr.URL != expectedURL {
+			t.Errorf("expected URL %q, got %q", expectedURL, apiErr.URL)
+		}
+
+		if apiErr.Code != expectedCode {
+			t.Errorf("expected code %q, got %q", expectedCode, apiErr.Code)
+		}
+
+		if apiErr.Message != expectedMessage {
+			t.Errorf("expected message %q, got %q", expectedMessage, apiErr.Message)
+		}
+
+		if string(apiErr.Body) != body {
+			t.Errorf("expected body %q, got %q", body, apiErr.Body)
+		}
+	}
+
+	test(
+		t,
+		http.StatusUnauthorized,
+		`{"statusCode": 401, "message": "Access denied due to invalid subscription key."}`,
+		"",
+		"Access denied due to invalid subscription key.",
+	)
+	test(
+		t,
+		http.StatusNotFound,
+		`{"Code": "WorkflowNotFound", "Message": "Workflow 1 not found."}`,
+		"WorkflowNotFound",
+		"Workflow 1 not found.",
+	)
+	test(
+		t,
+		http.StatusBadRequest,
+		`{"error": {"code": "InvalidProcess", "message": "Unknown process."}}`,
+		"InvalidProcess",
+		"Unknown process.",
+	)
+	test(t, http.StatusServiceUnavailable, "upstream unavailable", "", "")
+}
+
+func TestAPIErrorError(t *testing.T) {
+	test := func(t testing.TB, err *APIError, expected string) {
+		if actual := err.Error(); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(
+		t,
+		&APIError{
+			StatusCode: http.StatusNotFound,
+			Method:     http.MethodGet,
+			URL:        "https://example.com/api/workflows/1",
+			Code:       "WorkflowNotFound",
+			Message:    "Workflow 1 not found.",
+		},
+		"GET https://example.com/api/workflows/1: 404 Not Found: WorkflowNotFound: Workflow 1 not found.",
+	)
+	test(
+		t,
+		&APIError{
+			StatusCode: http.StatusServiceUnavailable,
+			Method:     http.MethodGet,
+			URL:        "https://example.com/api/workflows",
+			Body:       []byte("upstream unavailable\n"),
+		},
+		"GET https://example.com/api/workflows: 503 Service Unavailable: upstream unavailable",
+	)
+}
+
+func TestUnsuccessfulWorkflowErrorError(t *testing.T) {
+	err := NewUnsuccessfulWorkflowError(Workflow{
+		ID:      1597,
+		Status:  StatusFailed,
+		Message: "input not found",
+	})
+
+	expected := "workflow 1597 unsuccessful: failed (50000): input not found"
+
+	if actual := err.Error(); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
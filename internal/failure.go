@@ -0,0 +1,94 @@
+package internal
+
+import "regexp"
+
+// FailureInfo explains why a workflow failed and how to fix it.
+type FailureInfo struct {
+	Explanation string `yaml:"explanation"`
+	Cause       string `yaml:"cause,omitempty"`
+	Fix         string `yaml:"fix,omitempty"`
+
+	// FromMessage is set if the failure was recognized by its message
+	// instead of its failure code, which is less certain.
+	FromMessage bool `yaml:"-"`
+}
+
+// FailureCodes maps workflow failure codes (see `Workflow.FailureCode`) to
+// explanations. Codes can be added in the configuration file, e.g.,
+//
+//	failure-codes:
+//	  301:
+//	    explanation: The input file could not be read.
+//	    fix: Re-upload the input file.
+type FailureCodes map[int]FailureInfo
+
+// defaultFailureCodes is the built-in catalog of failure codes, from the
+// Microsoft Genomics troubleshooting guide.
+var defaultFailureCodes = FailureCodes{
+	701: {
+		Explanation: "A read is longer than the maximum read length.",
+		Cause:       "The input file is corrupt, e.g., two reads were concatenated.",
+		Fix:         "Check the integrity of the input file, then resubmit.",
+	},
+	702: {
+		Explanation: "A read has a different number of bases and quality scores.",
+		Cause:       "The input file is corrupt or truncated.",
+		Fix:         "Check the integrity of the input file, then resubmit.",
+	},
+}
+
+// failureRule explains failures whose message matches a pattern.
+type failureRule struct {
+	pattern *regexp.Regexp
+	info    FailureInfo
+}
+
+// defaultFailureRules explain failures without a known failure code by Azure
+// Storage error codes in their message, in order.
+var defaultFailureRules = []failureRule{
+	{
+		pattern: regexp.MustCompile(`\b(AuthenticationFailed|AuthorizationFailure|AuthorizationPermissionMismatch)\b`),
+		info: FailureInfo{
+			Explanation: "The service was denied access to the input or output storage.",
+			Cause:       "The SAS is invalid, expired, or lacks permissions, e.g., read on the input blob or write on the output container.",
+			Fix:         "Check the storage credentials and the system clock, then resubmit to sign a new SAS.",
+		},
+	},
+	{
+		pattern: regexp.MustCompile(`\b(BlobNotFound|ContainerNotFound)\b`),
+		info: FailureInfo{
+			Explanation: "The input blob or output container does not exist.",
+			Fix:         "Check the input blob and output container names, then resubmit.",
+		},
+	},
+}
+
+// ExplainFailure returns an explanation of the failure of the workflow, if
+// any. The failure code is looked up in the codes first, then in the
+// built-in catalog. Failures without a known code may be recognized by an
+// Azure Storage error code in their message, which is marked by
+// `FailureInfo.FromMessage`.
+func ExplainFailure(workflow *Workflow, codes FailureCodes) (FailureInfo, bool) {
+	if workflow.Status != StatusFailed && workflow.FailureCode == 0 {
+		return FailureInfo{}, false
+	}
+
+	if workflow.FailureCode != 0 {
+		for _, codes := range []FailureCodes{codes, defaultFailureCodes} {
+			if info, ok := codes[workflow.FailureCode]; ok {
+				info.FromMessage = false
+				return info, true
+			}
+		}
+	}
+
+	for _, rule := range defaultFailureRules {
+		if rule.pattern.MatchString(workflow.Message) {
+			info := rule.info
+			info.FromMessage = true
+			return info, true
+		}
+	}
+
+	return FailureInfo{}, false
+}
@@ -0,0 +1,140 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestExplainFailure(t *testing.T) {
+	codes := FailureCodes{
+		301: {Explanation: "Custom explanation."},
+		701: {Explanation: "Overridden explanation."},
+	}
+
+	test := func(t testing.TB, workflow Workflow, expected string, expectedFromMessage bool) {
+		t.Helper()
+
+		info, ok := ExplainFailure(&workflow, codes)
+
+		if len(expected) == 0 {
+			if ok {
+				t.Errorf("expected no explanation, got %+v", info)
+			}
+
+			return
+		}
+
+		if !ok || info.Explanation != expected || info.FromMessage != expectedFromMessage {
+			t.Errorf("expected %q (from message = %v), got %+v", expected, expectedFromMessage, info)
+		}
+	}
+
+	test(t, Workflow{Status: StatusFailed, FailureCode: 301, Message: "invalid input"}, "Custom explanation.", false)
+	test(t, Workflow{Status: StatusFailed, FailureCode: 701, Message: "Read r1 has 1000 bases"}, "Overridden explanation.", false)
+	test(t, Workflow{Status: StatusFailed, FailureCode: 702}, "A read has a different number of bases and quality scores.", false)
+	test(
+		t,
+		Workflow{Status: StatusFailed, FailureCode: 999, Message: "This request is not authorized to perform this operation. ErrorCode: AuthorizationFailure"},
+		"The service was denied access to the input or output storage.",
+		true,
+	)
+	test(t, Workflow{Status: StatusFailed, Message: "ErrorCode: BlobNotFound"}, "The input blob or output container does not exist.", true)
+	test(t, Workflow{Status: StatusSuccess, Message: "BAM file processed"}, "", false)
+
+	// Messages that only resemble known failures are not explained.
+	for _, message := range []string{
+		"Internal error",
+		"Invalid input: BAM file is truncated",
+		"Storage account location differs from the service",
+		"Quota exceeded",
+		"Unexpected EOF",
+		"Access forbidden (403)",
+		"The token has expired",
+		"authenticationfailed",
+	} {
+		test(t, Workflow{Status: StatusFailed, FailureCode: 999, Message: message}, "", false)
+	}
+}
+
+func TestWriteWorkflowWithFailureExplanation(t *testing.T) {
+	writer, err := NewWorkflowWriter(OutputOptions{
+		FailureCodes: FailureCodes{
+			301: {Explanation: "Reads are too long.", Fix: "Trim the reads."},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := writer.WriteWorkflow(&buf, newTestWorkflows()[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"Workflow ID     : 2\n" +
+		"Status          : failed (50000)\n" +
+		"Failure Code    : 301\n" +
+		"Message         : invalid input\n" +
+		"Explanation     : Reads are too long.\n" +
+		"Suggested Fix   : Trim the reads.\n"
+
+	if actual := buf.String(); !strings.HasPrefix(actual, expected) {
+		t.Errorf("mismatch (-actual, +expected):\n%s", cmp.Diff(actual, expected))
+	}
+
+	workflow := newTestWorkflows()[1]
+	workflow.FailureCode = 0
+	workflow.Message = "ErrorCode: ContainerNotFound"
+
+	buf.Reset()
+
+	if err := writer.WriteWorkflow(&buf, workflow); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedLine := "Explanation     : The input blob or output container does not exist. (guessed from the message)\n"
+
+	if actual := buf.String(); !strings.Contains(actual, expectedLine) {
+		t.Errorf("expected %q in:\n%s", expectedLine, actual)
+	}
+}
+
+func TestLoadConfigWithFailureCodes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	data := "" +
+		"failure-codes:\n" +
+		"  301:\n" +
+		"    explanation: Reads are too long.\n" +
+		"    cause: The aligner supports reads up to 250 bp.\n" +
+		"    fix: Trim the reads.\n"
+
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfig(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := FailureCodes{
+		301: {
+			Explanation: "Reads are too long.",
+			Cause:       "The aligner supports reads up to 250 bp.",
+			Fix:         "Trim the reads.",
+		},
+	}
+
+	if diff := cmp.Diff(config.FailureCodes, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
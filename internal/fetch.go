@@ -1,18 +1,28 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
 )
 
 func CancelWorkflow(client Client, ID WorkflowID) (Workflow, error) {
+	return CancelWorkflowContext(context.Background(), client, ID)
+}
+
+// CancelWorkflowContext is like `CancelWorkflow` but stops when the context is
+// done.
+func CancelWorkflowContext(ctx context.Context, client Client, ID WorkflowID) (Workflow, error) {
 	workflow := Workflow{}
 
 	endpoint := fmt.Sprintf("/api/workflows/%v", ID)
-	response, err := client.Delete(endpoint)
+	response, err := client.DeleteContext(ctx, endpoint)
 
 	if err != nil {
 		return workflow, err
@@ -28,28 +38,43 @@ func CancelWorkflow(client Client, ID WorkflowID) (Workflow, error) {
 }
 
 func FetchWorkflows(client Client) ([]Workflow, error) {
-	workflows := []Workflow{}
-
-	response, err := client.Get("/api/workflows?$orderby=CreatedDate%20asc")
-
-	if err != nil {
-		return workflows, err
-	}
+	return FetchWorkflowsContext(context.Background(), client)
+}
 
-	defer response.Body.Close()
+// FetchWorkflowsContext is like `FetchWorkflows` but stops when the context is
+// done.
+func FetchWorkflowsContext(ctx context.Context, client Client) ([]Workflow, error) {
+	return fetchWorkflows(ctx, client, workflowsEndpoint(defaultOrderBy, "", 0, 0))
+}
 
-	if err := decodeJSON(response.Body, &workflows); err != nil {
-		return workflows, err
-	}
+// FetchFilteredWorkflows returns the workflows selected by the filter, oldest
+// first. See `ListWorkflows` to also sort and limit them.
+//
+// The filter is sent to the API as an OData `$filter` expression, as far as
+// it can be expressed, so fewer workflows are downloaded. If the API rejects
+// the expression, all workflows are fetched instead. Either way, the filter
+// is applied to the response, so the result is the same.
+func FetchFilteredWorkflows(client Client, filter WorkflowFilter) ([]Workflow, error) {
+	return FetchFilteredWorkflowsContext(context.Background(), client, filter)
+}
 
-	return workflows, nil
+// FetchFilteredWorkflowsContext is like `FetchFilteredWorkflows` but stops
+// when the context is done.
+func FetchFilteredWorkflowsContext(ctx context.Context, client Client, filter WorkflowFilter) ([]Workflow, error) {
+	return ListWorkflowsContext(ctx, client, WorkflowListOptions{Filter: filter})
 }
 
 func FetchWorkflow(client Client, ID WorkflowID) (Workflow, error) {
+	return FetchWorkflowContext(context.Background(), client, ID)
+}
+
+// FetchWorkflowContext is like `FetchWorkflow` but stops when the context is
+// done.
+func FetchWorkflowContext(ctx context.Context, client Client, ID WorkflowID) (Workflow, error) {
 	workflow := Workflow{}
 
 	endpoint := fmt.Sprintf("/api/workflows/%v", ID)
-	response, err := client.Get(endpoint)
+	response, err := client.GetContext(ctx, endpoint)
 
 	if err != nil {
 		return workflow, err
@@ -65,27 +90,86 @@ func FetchWorkflow(client Client, ID WorkflowID) (Workflow, error) {
 }
 
 func SubmitWorkflow(client Client, config SubmitConfig) (Workflow, error) {
+	return SubmitWorkflowContext(context.Background(), client, config)
+}
+
+// SubmitWorkflowContext is like `SubmitWorkflow` but stops when the context is
+// done, including while requesting user delegation keys to sign SAS.
+//
+// A submission token is appended to the description. If the request fails
+// with a transient error, it may have still created a workflow, so before
+// each retry, the workflows created since the submission started are queried
+// for the token, and an existing workflow is returned instead of submitting
+// again.
+func SubmitWorkflowContext(ctx context.Context, client Client, config SubmitConfig) (Workflow, error) {
 	workflow := Workflow{}
 
-	newWorkflow, err := buildSubmitWorkflowPayload(config)
+	newWorkflow, err := buildSubmitWorkflowPayload(ctx, config, client.blobServiceClientOptions())
 
 	if err != nil {
 		return workflow, err
 	}
 
-	response, err := client.Post("/api/workflows", newWorkflow)
+	token, err := newSubmissionToken()
 
 	if err != nil {
 		return workflow, err
 	}
 
-	defer response.Body.Close()
+	newWorkflow.Description = withSubmissionToken(newWorkflow.Description, token)
+	start := time.Now()
 
-	if err := decodeJSON(response.Body, &workflow); err != nil {
-		return workflow, err
-	}
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			existingWorkflow, ok, err := findWorkflowBySubmissionToken(ctx, client, token, start)
 
-	return workflow, nil
+			if err != nil {
+				return workflow, fmt.Errorf("could not check for an existing submission: %w", err)
+			}
+
+			if ok {
+				slog.Info("submit: found existing workflow", "workflowID", existingWorkflow.ID)
+				return existingWorkflow, nil
+			}
+		}
+
+		response, err := client.PostContext(ctx, "/api/workflows", newWorkflow)
+
+		if err == nil {
+			defer response.Body.Close()
+
+			if err := decodeJSON(response.Body, &workflow); err != nil {
+				return workflow, err
+			}
+
+			return workflow, nil
+		}
+
+		if attempt >= client.submitRetryMax || !isRetryableSubmitError(ctx, err, client.retryableStatusCodes) {
+			return workflow, err
+		}
+
+		wait := client.httpClient.Backoff(
+			client.httpClient.RetryWaitMin,
+			client.httpClient.RetryWaitMax,
+			attempt,
+			nil,
+		)
+
+		var apiErr *APIError
+
+		if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+			wait = apiErr.RetryAfter
+		}
+
+		slog.Warn("submit failed; retrying", "error", err, "wait", wait, "remaining", client.submitRetryMax-attempt)
+
+		select {
+		case <-ctx.Done():
+			return workflow, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
 }
 
 func decodeJSON(reader io.Reader, value interface{}) error {
@@ -93,16 +177,20 @@ func decodeJSON(reader io.Reader, value interface{}) error {
 	return decoder.Decode(value)
 }
 
-func buildSubmitWorkflowPayload(config SubmitConfig) (NewWorkflow, error) {
+func buildSubmitWorkflowPayload(
+	ctx context.Context,
+	config SubmitConfig,
+	options BlobServiceClientOptions,
+) (NewWorkflow, error) {
 	newWorkflow := NewWorkflow{}
 
-	blobNameWithSAS, err := generateInputBlobSAS(config.Input)
+	blobNameWithSAS, err := generateInputBlobSAS(ctx, config.Input, options)
 
 	if err != nil {
 		return newWorkflow, err
 	}
 
-	containerSAS, err := generateOutputContainerSAS(config.Output)
+	containerSAS, err := generateOutputContainerSAS(ctx, config.Output, options)
 
 	if err != nil {
 		return newWorkflow, err
@@ -136,18 +224,16 @@ func buildSubmitWorkflowPayload(config SubmitConfig) (NewWorkflow, error) {
 	return newWorkflow, nil
 }
 
-func generateInputBlobSAS(config InputConfig) (string, error) {
-	inputBlobServiceClient, err := NewBlobServiceClient(
-		config.Storage.AccountName,
-		config.Storage.AccountKey,
-	)
+func generateInputBlobSAS(ctx context.Context, config InputConfig, options BlobServiceClientOptions) (string, error) {
+	inputBlobServiceClient, err := NewBlobServiceClientWithOptions(config.Storage, options)
 
 	if err != nil {
 		return "", err
 	}
 
 	blobName := config.BlobName
-	blobSAS, err := inputBlobServiceClient.GenerateBlobSAS(
+	blobSAS, err := inputBlobServiceClient.GenerateBlobSASContext(
+		ctx,
 		config.Storage.ContainerName,
 		blobName,
 		sas.BlobPermissions{Read: true},
@@ -162,17 +248,19 @@ func generateInputBlobSAS(config InputConfig) (string, error) {
 	return blobNameWithSAS, nil
 }
 
-func generateOutputContainerSAS(config OutputConfig) (string, error) {
-	outputBlobServiceClient, err := NewBlobServiceClient(
-		config.Storage.AccountName,
-		config.Storage.AccountKey,
-	)
+func generateOutputContainerSAS(
+	ctx context.Context,
+	config OutputConfig,
+	options BlobServiceClientOptions,
+) (string, error) {
+	outputBlobServiceClient, err := NewBlobServiceClientWithOptions(config.Storage, options)
 
 	if err != nil {
 		return "", err
 	}
 
-	return outputBlobServiceClient.GenerateContainerSAS(
+	return outputBlobServiceClient.GenerateContainerSASContext(
+		ctx,
 		config.Storage.ContainerName,
 		sas.ContainerPermissions{Delete: true, Read: true, Write: true},
 	)
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -151,7 +152,7 @@ func TestBuildSubmitWorkflowPayload(t *testing.T) {
 		IgnoreAzureRegion: true,
 	}
 
-	actual, err := buildSubmitWorkflowPayload(config)
+	actual, err := buildSubmitWorkflowPayload(context.Background(), config, BlobServiceClientOptions{})
 
 	if err != nil {
 		t.Fatal(err)
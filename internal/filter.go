@@ -0,0 +1,466 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// Pattern matches a string with a glob or, if enclosed in slashes, a regular
+// expression, e.g., `sample-*` or `/^sample-\d+$/`.
+//
+// A glob matches the whole string, where `*` matches any sequence of
+// characters and `?` matches any single character. A regular expression
+// matches any part of the string unless anchored.
+type Pattern struct {
+	raw string
+	re  *regexp.Regexp
+
+	isRegexp bool
+}
+
+// ParsePattern parses a glob or a regular expression enclosed in slashes.
+func ParsePattern(s string) (*Pattern, error) {
+	if len(s) >= 2 && strings.HasPrefix(s, "/") && strings.HasSuffix(s, "/") {
+		re, err := regexp.Compile(s[1 : len(s)-1])
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern: %q: %w", s, err)
+		}
+
+		return &Pattern{raw: s, re: re, isRegexp: true}, nil
+	}
+
+	var b strings.Builder
+
+	b.WriteString("^")
+
+	for _, r := range s {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+
+	b.WriteString("$")
+
+	return &Pattern{raw: s, re: regexp.MustCompile(b.String())}, nil
+}
+
+func (p *Pattern) String() string {
+	return p.raw
+}
+
+// MatchString returns whether the pattern matches s.
+func (p *Pattern) MatchString(s string) bool {
+	return p.re.MatchString(s)
+}
+
+// odataMatch is how an OData expression matches a literal.
+type odataMatch int
+
+const (
+	odataMatchEqual odataMatch = iota
+	odataMatchPrefix
+	odataMatchSuffix
+	odataMatchContains
+)
+
+// expression returns the OData expression matching the literal on the field.
+func (m odataMatch) expression(field string, literal string) string {
+	quoted := quoteODataString(literal)
+
+	switch m {
+	case odataMatchPrefix:
+		return fmt.Sprintf("startswith(%s,%s)", field, quoted)
+	case odataMatchSuffix:
+		return fmt.Sprintf("endswith(%s,%s)", field, quoted)
+	case odataMatchContains:
+		return fmt.Sprintf("contains(%s,%s)", field, quoted)
+	default:
+		return fmt.Sprintf("%s eq %s", field, quoted)
+	}
+}
+
+// odataLiteral returns the literal of the pattern and how it matches, if the
+// pattern has an OData equivalent. Only globs without `?` and with `*` at
+// most at either end are translated.
+func (p *Pattern) odataLiteral() (string, odataMatch, bool) {
+	if p.isRegexp || strings.Contains(p.raw, "?") {
+		return "", 0, false
+	}
+
+	hasPrefix := strings.HasSuffix(p.raw, "*")
+	hasSuffix := strings.HasPrefix(p.raw, "*")
+	literal := strings.TrimSuffix(strings.TrimPrefix(p.raw, "*"), "*")
+
+	if strings.Contains(literal, "*") {
+		return "", 0, false
+	}
+
+	switch {
+	case hasPrefix && hasSuffix:
+		return literal, odataMatchContains, true
+	case hasPrefix:
+		return literal, odataMatchPrefix, true
+	case hasSuffix:
+		return literal, odataMatchSuffix, true
+	default:
+		return literal, odataMatchEqual, true
+	}
+}
+
+// odata returns an OData expression equivalent to the pattern on the field,
+// if there is one.
+func (p *Pattern) odata(field string) (string, bool) {
+	literal, match, ok := p.odataLiteral()
+
+	if !ok {
+		return "", false
+	}
+
+	return match.expression(field, literal), true
+}
+
+// odataAllowingSuffix is like `odata`, but for a field that may have a suffix
+// appended, e.g., the submission token of a description. The expression may
+// match more than the pattern, so matches must be refined with `MatchString`.
+func (p *Pattern) odataAllowingSuffix(field string) (string, bool) {
+	literal, match, ok := p.odataLiteral()
+
+	if !ok {
+		return "", false
+	}
+
+	switch match {
+	case odataMatchEqual:
+		match = odataMatchPrefix
+	case odataMatchSuffix:
+		match = odataMatchContains
+	}
+
+	return match.expression(field, literal), true
+}
+
+func quoteODataString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// WorkflowFilter selects workflows by their fields. Zero values do not
+// filter.
+type WorkflowFilter struct {
+	// Statuses are the allowed statuses.
+	Statuses []Status
+
+	// FailedOnly selects only failed workflows.
+	FailedOnly bool
+
+	Process     *Pattern
+	Description *Pattern
+
+	// Since and Until bound the creation date. Since is inclusive, and
+	// Until is exclusive.
+	Since time.Time
+	Until time.Time
+
+	// MinID and MaxID bound the workflow ID, inclusively.
+	MinID WorkflowID
+	MaxID WorkflowID
+
+	// submissionToken selects the workflows submitted with the token (see
+	// `SubmissionToken`).
+	submissionToken string
+}
+
+// IsZero returns whether the filter selects all workflows.
+func (f *WorkflowFilter) IsZero() bool {
+	return len(f.Statuses) == 0 &&
+		!f.FailedOnly &&
+		f.Process == nil &&
+		f.Description == nil &&
+		f.Since.IsZero() &&
+		f.Until.IsZero() &&
+		f.MinID == 0 &&
+		f.MaxID == 0 &&
+		len(f.submissionToken) == 0
+}
+
+// Matches returns whether the workflow is selected by the filter.
+func (f *WorkflowFilter) Matches(workflow *Workflow) bool {
+	switch {
+	case len(f.Statuses) > 0 && !slices.Contains(f.Statuses, workflow.Status):
+		return false
+	case f.FailedOnly && workflow.Status != StatusFailed:
+		return false
+	case f.Process != nil && !f.Process.MatchString(workflow.Process):
+		return false
+	case f.Description != nil && !f.Description.MatchString(WithoutSubmissionToken(workflow.Description)):
+		return false
+	case !f.Since.IsZero() && workflow.CreatedDate.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !workflow.CreatedDate.Before(f.Until):
+		return false
+	case f.MinID > 0 && workflow.ID < f.MinID:
+		return false
+	case f.MaxID > 0 && workflow.ID > f.MaxID:
+		return false
+	case len(f.submissionToken) > 0 && !hasSubmissionToken(workflow.Description, f.submissionToken):
+		return false
+	default:
+		return true
+	}
+}
+
+// Select returns the workflows matched by the filter, in order.
+func (f *WorkflowFilter) Select(workflows []Workflow) []Workflow {
+	selected := []Workflow{}
+
+	for _, workflow := range workflows {
+		if f.Matches(&workflow) {
+			selected = append(selected, workflow)
+		}
+	}
+
+	return selected
+}
+
+// odata returns the filter as an OData `$filter` expression, or an empty
+// string if no part of it can be expressed. Parts that cannot be expressed,
+// e.g., regular expressions, are left to `Select`.
+func (f *WorkflowFilter) odata() string {
+	clauses := []string{}
+
+	if len(f.Statuses) > 0 {
+		terms := make([]string, len(f.Statuses))
+
+		for i, status := range f.Statuses {
+			terms[i] = fmt.Sprintf("Status eq %d", status)
+		}
+
+		if len(terms) == 1 {
+			clauses = append(clauses, terms[0])
+		} else {
+			clauses = append(clauses, "("+strings.Join(terms, " or ")+")")
+		}
+	}
+
+	if f.FailedOnly {
+		clauses = append(clauses, fmt.Sprintf("Status eq %d", StatusFailed))
+	}
+
+	if f.Process != nil {
+		if clause, ok := f.Process.odata("Process"); ok {
+			clauses = append(clauses, clause)
+		}
+	}
+
+	// Descriptions of workflows submitted by msgenctl end with a submission
+	// token, which is ignored by `Matches`.
+	if f.Description != nil {
+		if clause, ok := f.Description.odataAllowingSuffix("Description"); ok {
+			clauses = append(clauses, clause)
+		}
+	}
+
+	if !f.Since.IsZero() {
+		clauses = append(clauses, "CreatedDate ge "+f.Since.UTC().Format(time.RFC3339))
+	}
+
+	if !f.Until.IsZero() {
+		clauses = append(clauses, "CreatedDate lt "+f.Until.UTC().Format(time.RFC3339))
+	}
+
+	if f.MinID > 0 {
+		clauses = append(clauses, fmt.Sprintf("Id ge %d", f.MinID))
+	}
+
+	if f.MaxID > 0 {
+		clauses = append(clauses, fmt.Sprintf("Id le %d", f.MaxID))
+	}
+
+	if len(f.submissionToken) > 0 {
+		tag := submissionTag(f.submissionToken)
+		clauses = append(clauses, odataMatchContains.expression("Description", tag))
+	}
+
+	return strings.Join(clauses, " and ")
+}
+
+// isODataComplete returns whether all of the filter is expressed by `odata`,
+// i.e., the API returns only matching workflows if it accepts the expression.
+// Description expressions are never complete, as they ignore the submission
+// token.
+func (f *WorkflowFilter) isODataComplete() bool {
+	if f.Description != nil {
+		return false
+	}
+
+	if f.Process != nil {
+		if _, ok := f.Process.odata(""); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// ParseTimeBound parses a time given as an RFC 3339 timestamp (e.g.,
+// `2024-01-01T12:00:00Z`), a date in UTC (e.g., `2024-01-01`), the start of
+// a month in UTC (e.g., `2024-01`), or a duration before now (e.g., `36h` or
+// `7d`).
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339, time.DateOnly, "2006-01"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time: %q: expected a timestamp, date, month, or duration", s)
+}
+
+// ParseIDRange parses an inclusive range of workflow IDs, e.g., `100-200`.
+// Either bound may be omitted, e.g., `100-` or `-200`, and is returned as 0.
+func ParseIDRange(s string) (WorkflowID, WorkflowID, error) {
+	rawMin, rawMax, ok := strings.Cut(s, "-")
+
+	if !ok || (len(rawMin) == 0 && len(rawMax) == 0) {
+		return 0, 0, fmt.Errorf("invalid ID range: %q: expected <min>-<max>", s)
+	}
+
+	bounds := [2]WorkflowID{}
+
+	for i, raw := range []string{rawMin, rawMax} {
+		if len(raw) == 0 {
+			continue
+		}
+
+		n, err := strconv.Atoi(raw)
+
+		if err != nil || n <= 0 {
+			return 0, 0, fmt.Errorf("invalid ID range: %q: invalid ID %q", s, raw)
+		}
+
+		bounds[i] = WorkflowID(n)
+	}
+
+	if bounds[0] > 0 && bounds[1] > 0 && bounds[0] > bounds[1] {
+		return 0, 0, fmt.Errorf("invalid ID range: %q: min is greater than max", s)
+	}
+
+	return bounds[0], bounds[1], nil
+}
+
+// WorkflowFilterFromFlags builds a workflow filter from the flags `--status`,
+// `--failed-only`, `--process`, `--description`, `--since`, `--until`, and
+// `--id-range`.
+func WorkflowFilterFromFlags(flags *pflag.FlagSet, now time.Time) (WorkflowFilter, error) {
+	filter := WorkflowFilter{}
+
+	rawStatuses, err := flags.GetStringSlice("status")
+
+	if err != nil {
+		return filter, err
+	}
+
+	for _, rawStatus := range rawStatuses {
+		status, err := ParseStatus(rawStatus)
+
+		if err != nil {
+			return filter, err
+		}
+
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	filter.FailedOnly, err = flags.GetBool("failed-only")
+
+	if err != nil {
+		return filter, err
+	}
+
+	for _, field := range []struct {
+		name    string
+		pattern **Pattern
+	}{{"process", &filter.Process}, {"description", &filter.Description}} {
+		raw, err := flags.GetString(field.name)
+
+		if err != nil {
+			return filter, err
+		}
+
+		if len(raw) == 0 {
+			continue
+		}
+
+		pattern, err := ParsePattern(raw)
+
+		if err != nil {
+			return filter, fmt.Errorf("%s: %w", field.name, err)
+		}
+
+		*field.pattern = pattern
+	}
+
+	for _, field := range []struct {
+		name  string
+		value *time.Time
+	}{{"since", &filter.Since}, {"until", &filter.Until}} {
+		raw, err := flags.GetString(field.name)
+
+		if err != nil {
+			return filter, err
+		}
+
+		if len(raw) == 0 {
+			continue
+		}
+
+		t, err := ParseTimeBound(raw, now)
+
+		if err != nil {
+			return filter, fmt.Errorf("%s: %w", field.name, err)
+		}
+
+		*field.value = t
+	}
+
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return filter, errors.New("since must be before until")
+	}
+
+	rawIDRange, err := flags.GetString("id-range")
+
+	if err != nil {
+		return filter, err
+	}
+
+	if len(rawIDRange) > 0 {
+		filter.MinID, filter.MaxID, err = ParseIDRange(rawIDRange)
+
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
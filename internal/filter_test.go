@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func mustParsePattern(t testing.TB, s string) *Pattern {
+	t.Helper()
+
+	pattern, err := ParsePattern(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pattern
+}
+
+func TestPattern(t *testing.T) {
+	test := func(t testing.TB, pattern string, s string, expected bool) {
+		t.Helper()
+
+		if actual := mustParsePattern(t, pattern).MatchString(s); actual != expected {
+			t.Errorf("%s: %q: expected %v, got %v", pattern, s, expected, actual)
+		}
+	}
+
+	test(t, "sample-*", "sample-1", true)
+	test(t, "sample-*", "a sample-1", false)
+	test(t, "sample-?", "sample-10", false)
+	test(t, "*.bam", "a/b.bam", true)
+	test(t, "a.b", "axb", false)
+	test(t, `/^sample-\d+$/`, "sample-10", true)
+	test(t, `/\d+/`, "sample-10 x", true)
+	test(t, `/^\d+$/`, "sample-10", false)
+
+	if _, err := ParsePattern("/[/"); err == nil {
+		t.Error("expected error for an invalid regular expression")
+	}
+}
+
+func TestWorkflowFilterODataAndMatches(t *testing.T) {
+	workflows := newTestWorkflows()
+
+	test := func(t testing.TB, filter WorkflowFilter, expectedOData string, expectedIDs []WorkflowID) {
+		t.Helper()
+
+		if diff := cmp.Diff(filter.odata(), expectedOData); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+
+		ids := []WorkflowID{}
+
+		for _, workflow := range filter.Select(workflows) {
+			ids = append(ids, workflow.ID)
+		}
+
+		if diff := cmp.Diff(ids, expectedIDs); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+	}
+
+	test(t, WorkflowFilter{}, "", []WorkflowID{1, 2})
+	test(t, WorkflowFilter{FailedOnly: true}, "Status eq 50000", []WorkflowID{2})
+	test(
+		t,
+		WorkflowFilter{Statuses: []Status{StatusSuccess, StatusCancelled}},
+		"(Status eq 20000 or Status eq 60000)",
+		[]WorkflowID{1},
+	)
+	test(
+		t,
+		WorkflowFilter{Process: mustParsePattern(t, "snapgatk-*")},
+		"startswith(Process,'snapgatk-')",
+		[]WorkflowID{1},
+	)
+	test(
+		t,
+		WorkflowFilter{Description: mustParsePattern(t, "*one")},
+		"contains(Description,'one')",
+		[]WorkflowID{1},
+	)
+	test(
+		t,
+		WorkflowFilter{Description: mustParsePattern(t, "sample, one")},
+		"startswith(Description,'sample, one')",
+		[]WorkflowID{1},
+	)
+	test(
+		t,
+		WorkflowFilter{Description: mustParsePattern(t, "sample")},
+		"startswith(Description,'sample')",
+		[]WorkflowID{},
+	)
+	test(
+		t,
+		WorkflowFilter{Description: mustParsePattern(t, "*'s*")},
+		"contains(Description,'''s')",
+		[]WorkflowID{},
+	)
+	test(
+		t,
+		WorkflowFilter{Description: mustParsePattern(t, "/, o/")},
+		"",
+		[]WorkflowID{1},
+	)
+	test(
+		t,
+		WorkflowFilter{Since: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		"CreatedDate ge 2024-01-01T12:00:00Z",
+		[]WorkflowID{2},
+	)
+	test(
+		t,
+		WorkflowFilter{Until: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+		"CreatedDate lt 2024-01-02T00:00:00Z",
+		[]WorkflowID{1},
+	)
+	test(t, WorkflowFilter{MinID: 2, MaxID: 5}, "Id ge 2 and Id le 5", []WorkflowID{2})
+	test(
+		t,
+		WorkflowFilter{FailedOnly: true, Process: mustParsePattern(t, "snapgatk-*")},
+		"Status eq 50000 and startswith(Process,'snapgatk-')",
+		[]WorkflowID{},
+	)
+}
+
+func TestParseTimeBound(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	test := func(t testing.TB, s string, expected time.Time) {
+		t.Helper()
+
+		actual, err := ParseTimeBound(s, now)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !actual.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", s, expected, actual)
+		}
+	}
+
+	test(t, "2024-01-01", time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	test(t, "2024-01-01T12:30:00+02:00", time.Date(2024, 1, 1, 10, 30, 0, 0, time.UTC))
+	test(t, "2024-02", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+	test(t, "7d", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC))
+	test(t, "36h", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC))
+
+	for _, s := range []string{"yesterday", "-1h", "2024-13-01"} {
+		if _, err := ParseTimeBound(s, now); err == nil {
+			t.Errorf("%s: expected error", s)
+		}
+	}
+}
+
+func TestParseIDRange(t *testing.T) {
+	test := func(t testing.TB, s string, expectedMin WorkflowID, expectedMax WorkflowID) {
+		t.Helper()
+
+		actualMin, actualMax, err := ParseIDRange(s)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actualMin != expectedMin || actualMax != expectedMax {
+			t.Errorf("%s: expected %d-%d, got %d-%d", s, expectedMin, expectedMax, actualMin, actualMax)
+		}
+	}
+
+	test(t, "100-200", 100, 200)
+	test(t, "100-", 100, 0)
+	test(t, "-200", 0, 200)
+	test(t, "5-5", 5, 5)
+
+	for _, s := range []string{"", "-", "100", "a-b", "200-100", "0-5"} {
+		if _, _, err := ParseIDRange(s); err == nil {
+			t.Errorf("%q: expected error", s)
+		}
+	}
+}
+
+func TestWorkflowFilterFromFlags(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		flags.StringSlice("status", nil, "")
+		flags.Bool("failed-only", false, "")
+		flags.String("process", "", "")
+		flags.String("description", "", "")
+		flags.String("since", "", "")
+		flags.String("until", "", "")
+		flags.String("id-range", "", "")
+		return flags
+	}
+
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	flags := newFlags()
+
+	if err := flags.Parse([]string{
+		"--status", "queued,working",
+		"--process", "snapgatk-*",
+		"--since", "1d",
+		"--id-range", "10-",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	filter, err := WorkflowFilterFromFlags(flags, now)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(Status eq 1000 or Status eq 10000) and startswith(Process,'snapgatk-') and " +
+		"CreatedDate ge 2024-03-09T12:00:00Z and Id ge 10"
+
+	if diff := cmp.Diff(filter.odata(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	for _, args := range [][]string{
+		{"--status", "done"},
+		{"--description", "/(/"},
+		{"--since", "2024-02-01", "--until", "2024-01-01"},
+		{"--id-range", "x"},
+	} {
+		flags := newFlags()
+
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := WorkflowFilterFromFlags(flags, now); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
+
+func TestFetchFilteredWorkflows(t *testing.T) {
+	test := func(t testing.TB, supportsFilter bool) {
+		t.Helper()
+
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+
+			filter := r.URL.Query().Get("$filter")
+
+			switch {
+			case len(filter) > 0 && !supportsFilter:
+				rw.WriteHeader(http.StatusBadRequest)
+				rw.Write([]byte(`{"Message":"The query specified in the URI is not valid."}`))
+			case len(filter) > 0:
+				if filter != "Status eq 50000" {
+					t.Errorf("unexpected filter: %q", filter)
+				}
+
+				rw.Write([]byte(`[{"Id":2,"Status":50000}]`))
+			default:
+				rw.Write([]byte(`[{"Id":1,"Status":20000},{"Id":2,"Status":50000}]`))
+			}
+		}))
+
+		defer server.Close()
+
+		client := NewClient(server.URL, "secret")
+		workflows, err := FetchFilteredWorkflowsContext(context.Background(), client, WorkflowFilter{FailedOnly: true})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(workflows) != 1 || workflows[0].ID != 2 {
+			t.Errorf("expected workflow 2, got %+v", workflows)
+		}
+
+		expectedRequests := int32(1)
+
+		if !supportsFilter {
+			expectedRequests = 2
+		}
+
+		if n := requests.Load(); n != expectedRequests {
+			t.Errorf("expected %d requests, got %d", expectedRequests, n)
+		}
+	}
+
+	test(t, true)
+	test(t, false)
+}
@@ -0,0 +1,485 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// JSONPath is a kubectl-style JSONPath template, e.g.,
+// `{range .items[*]}{.id}{"\t"}{.status}{"\n"}{end}`.
+//
+// Text outside of braces is written as is. Within braces, the supported
+// expressions are
+//
+//   - a path of fields (`.name`, `['name']`), indexes (`[0]`, `[-1]`),
+//     slices (`[1:3]`), wildcards (`[*]`, `.*`), and recursive descent
+//     (`..name`), relative to the current value. `$` and `.` are the current
+//     value. Fields are matched case-insensitively if there is no exact
+//     match, so `.ID` matches `id`.
+//   - a string literal, e.g., `{"\n"}`.
+//   - `range <path>` and `end`, which repeat the enclosed template for each
+//     result of the path.
+//
+// Multiple results of a path are separated by spaces. Strings are written
+// without quotes, and objects and lists as JSON.
+type JSONPath struct {
+	nodes []jsonPathNode
+}
+
+type jsonPathNode struct {
+	text     string
+	path     []jsonPathStep
+	isPath   bool
+	isRange  bool
+	children []jsonPathNode
+}
+
+type jsonPathStepKind int
+
+const (
+	jsonPathField jsonPathStepKind = iota
+	jsonPathIndex
+	jsonPathSlice
+	jsonPathWildcard
+	// jsonPathRecursive selects the value and all of its descendants. Values
+	// that the rest of the path does not apply to are skipped.
+	jsonPathRecursive
+)
+
+type jsonPathStep struct {
+	kind  jsonPathStepKind
+	name  string
+	index int
+	// start and end are the bounds of a slice. A nil bound is open.
+	start *int
+	end   *int
+}
+
+// ParseJSONPath parses a JSONPath template. See `JSONPath`.
+func ParseJSONPath(s string) (*JSONPath, error) {
+	// stack holds the nodes of each open range, with the top-level nodes
+	// first.
+	stack := [][]jsonPathNode{{}}
+	ranges := []jsonPathNode{}
+
+	appendNode := func(node jsonPathNode) {
+		stack[len(stack)-1] = append(stack[len(stack)-1], node)
+	}
+
+	for len(s) > 0 {
+		start := strings.IndexByte(s, '{')
+
+		if start < 0 {
+			appendNode(jsonPathNode{text: s})
+			break
+		}
+
+		if start > 0 {
+			appendNode(jsonPathNode{text: s[:start]})
+		}
+
+		end, err := findClosingBrace(s, start)
+
+		if err != nil {
+			return nil, err
+		}
+
+		expression := strings.TrimSpace(s[start+1 : end])
+		s = s[end+1:]
+
+		switch {
+		case strings.HasPrefix(expression, `"`):
+			text, err := strconv.Unquote(expression)
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid string literal: %s", expression)
+			}
+
+			appendNode(jsonPathNode{text: text})
+		case expression == "end":
+			if len(ranges) == 0 {
+				return nil, errors.New("unexpected {end}")
+			}
+
+			node := ranges[len(ranges)-1]
+			node.children = stack[len(stack)-1]
+			ranges = ranges[:len(ranges)-1]
+			stack = stack[:len(stack)-1]
+			appendNode(node)
+		case strings.HasPrefix(expression, "range "):
+			path, err := parseJSONPathSteps(strings.TrimSpace(strings.TrimPrefix(expression, "range ")))
+
+			if err != nil {
+				return nil, err
+			}
+
+			ranges = append(ranges, jsonPathNode{path: path, isRange: true})
+			stack = append(stack, []jsonPathNode{})
+		default:
+			path, err := parseJSONPathSteps(expression)
+
+			if err != nil {
+				return nil, err
+			}
+
+			appendNode(jsonPathNode{path: path, isPath: true})
+		}
+	}
+
+	if len(ranges) > 0 {
+		return nil, errors.New("missing {end}")
+	}
+
+	return &JSONPath{nodes: stack[0]}, nil
+}
+
+// findClosingBrace returns the index of the brace closing the one at start,
+// skipping braces in string literals.
+func findClosingBrace(s string, start int) (int, error) {
+	inString := false
+
+	for i := start + 1; i < len(s); i++ {
+		switch {
+		case inString && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			inString = !inString
+		case !inString && s[i] == '}':
+			return i, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unclosed expression: %s", s[start:])
+}
+
+func parseJSONPathSteps(s string) ([]jsonPathStep, error) {
+	steps := []jsonPathStep{}
+	rest := strings.TrimPrefix(s, "$")
+
+	if rest == "." {
+		return steps, nil
+	}
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			if strings.HasPrefix(rest, "..") {
+				steps = append(steps, jsonPathStep{kind: jsonPathRecursive})
+				rest = rest[1:]
+
+				if strings.HasPrefix(rest, ".[") {
+					rest = rest[1:]
+					continue
+				}
+			}
+
+			rest = rest[1:]
+			n := strings.IndexAny(rest, ".[")
+
+			if n < 0 {
+				n = len(rest)
+			}
+
+			name := rest[:n]
+			rest = rest[n:]
+
+			if len(name) == 0 {
+				return nil, fmt.Errorf("invalid path: %s: missing field name", s)
+			}
+
+			if name == "*" {
+				steps = append(steps, jsonPathStep{kind: jsonPathWildcard})
+			} else {
+				steps = append(steps, jsonPathStep{kind: jsonPathField, name: name})
+			}
+		case '[':
+			n := strings.IndexByte(rest, ']')
+
+			if n < 0 {
+				return nil, fmt.Errorf("invalid path: %s: missing ]", s)
+			}
+
+			step, err := parseJSONPathSubscript(rest[1:n])
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid path: %s: %w", s, err)
+			}
+
+			steps = append(steps, step)
+			rest = rest[n+1:]
+		default:
+			return nil, fmt.Errorf("invalid path: %s: expected . or [", s)
+		}
+	}
+
+	return steps, nil
+}
+
+func parseJSONPathSubscript(s string) (jsonPathStep, error) {
+	if s == "*" {
+		return jsonPathStep{kind: jsonPathWildcard}, nil
+	}
+
+	if unquoted, err := strconv.Unquote(strings.ReplaceAll(s, "'", `"`)); err == nil {
+		return jsonPathStep{kind: jsonPathField, name: unquoted}, nil
+	}
+
+	if rawStart, rawEnd, ok := strings.Cut(s, ":"); ok {
+		step := jsonPathStep{kind: jsonPathSlice}
+
+		for _, bound := range []struct {
+			raw   string
+			value **int
+		}{{rawStart, &step.start}, {rawEnd, &step.end}} {
+			if len(bound.raw) == 0 {
+				continue
+			}
+
+			n, err := strconv.Atoi(bound.raw)
+
+			if err != nil {
+				return step, fmt.Errorf("invalid slice bound: %q", bound.raw)
+			}
+
+			*bound.value = &n
+		}
+
+		return step, nil
+	}
+
+	n, err := strconv.Atoi(s)
+
+	if err != nil {
+		return jsonPathStep{}, fmt.Errorf("invalid subscript: %q", s)
+	}
+
+	return jsonPathStep{kind: jsonPathIndex, index: n}, nil
+}
+
+// Execute writes the template with the given data, which must be JSON-like,
+// i.e., made of `map[string]any`, `[]any`, and scalars, as decoded by
+// `encoding/json` (see `toJSONValue`).
+func (p *JSONPath) Execute(w io.Writer, data any) error {
+	return executeJSONPathNodes(w, p.nodes, data)
+}
+
+func executeJSONPathNodes(w io.Writer, nodes []jsonPathNode, data any) error {
+	for _, node := range nodes {
+		switch {
+		case node.isRange:
+			values, err := evaluateJSONPath(node.path, data)
+
+			if err != nil {
+				return err
+			}
+
+			for _, value := range values {
+				if err := executeJSONPathNodes(w, node.children, value); err != nil {
+					return err
+				}
+			}
+		case node.isPath:
+			values, err := evaluateJSONPath(node.path, data)
+
+			if err != nil {
+				return err
+			}
+
+			texts := make([]string, len(values))
+
+			for i, value := range values {
+				text, err := formatJSONPathValue(value)
+
+				if err != nil {
+					return err
+				}
+
+				texts[i] = text
+			}
+
+			if _, err := io.WriteString(w, strings.Join(texts, " ")); err != nil {
+				return err
+			}
+		default:
+			if _, err := io.WriteString(w, node.text); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func evaluateJSONPath(steps []jsonPathStep, data any) ([]any, error) {
+	values := []any{data}
+
+	afterRecursive := false
+
+	for _, step := range steps {
+		next := []any{}
+
+		for _, value := range values {
+			results, err := evaluateJSONPathStep(step, value)
+
+			if err != nil && afterRecursive {
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+
+			next = append(next, results...)
+		}
+
+		values = next
+		afterRecursive = afterRecursive || step.kind == jsonPathRecursive
+	}
+
+	return values, nil
+}
+
+func evaluateJSONPathStep(step jsonPathStep, value any) ([]any, error) {
+	switch step.kind {
+	case jsonPathField:
+		object, ok := value.(map[string]any)
+
+		if !ok {
+			return nil, fmt.Errorf("%s is not found", step.name)
+		}
+
+		if field, ok := object[step.name]; ok {
+			return []any{field}, nil
+		}
+
+		for key, field := range object {
+			if strings.EqualFold(key, step.name) {
+				return []any{field}, nil
+			}
+		}
+
+		return nil, fmt.Errorf("%s is not found", step.name)
+	case jsonPathWildcard:
+		switch v := value.(type) {
+		case []any:
+			return v, nil
+		case map[string]any:
+			values := []any{}
+
+			for _, key := range slices.Sorted(maps.Keys(v)) {
+				values = append(values, v[key])
+			}
+
+			return values, nil
+		default:
+			return nil, nil
+		}
+	case jsonPathRecursive:
+		return descendants(value), nil
+	}
+
+	list, ok := value.([]any)
+
+	if !ok {
+		return nil, errors.New("subscript of a value that is not a list")
+	}
+
+	if step.kind == jsonPathIndex {
+		i := step.index
+
+		if i < 0 {
+			i += len(list)
+		}
+
+		if i < 0 || i >= len(list) {
+			return nil, fmt.Errorf("index out of range: %d", step.index)
+		}
+
+		return []any{list[i]}, nil
+	}
+
+	start, end := 0, len(list)
+
+	if step.start != nil {
+		start = clampIndex(*step.start, len(list))
+	}
+
+	if step.end != nil {
+		end = clampIndex(*step.end, len(list))
+	}
+
+	if start >= end {
+		return []any{}, nil
+	}
+
+	return list[start:end], nil
+}
+
+// descendants returns the value and all values nested in it, depth first,
+// with object fields in key order.
+func descendants(value any) []any {
+	values := []any{value}
+
+	switch v := value.(type) {
+	case []any:
+		for _, item := range v {
+			values = append(values, descendants(item)...)
+		}
+	case map[string]any:
+		for _, key := range slices.Sorted(maps.Keys(v)) {
+			values = append(values, descendants(v[key])...)
+		}
+	}
+
+	return values
+}
+
+func clampIndex(i int, n int) int {
+	if i < 0 {
+		i += n
+	}
+
+	return min(max(i, 0), n)
+}
+
+func formatJSONPathValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		return string(data), err
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// toJSONValue converts v to a JSON-like value for `JSONPath.Execute`.
+func toJSONValue(v any) (any, error) {
+	data, err := json.Marshal(v)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var value any
+
+	// Numbers are kept as written, e.g., large IDs are not rounded.
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
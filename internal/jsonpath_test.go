@@ -0,0 +1,156 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJSONPath(t *testing.T) {
+	data, err := toJSONValue(map[string]any{
+		"items": []map[string]any{
+			{
+				"id":     1,
+				"status": "success",
+				"tags":   []string{"a", "b"},
+				"meta":   map[string]any{"owner": "lab", "run.id": "r1", "count": 3},
+			},
+			{"id": 2, "status": "failed", "tags": []string{}},
+			{"id": 3, "status": "cancelled", "tags": []string{"c"}},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	test := func(t testing.TB, template string, expected string) {
+		t.Helper()
+
+		path, err := ParseJSONPath(template)
+
+		if err != nil {
+			t.Fatalf("%s: %v", template, err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := path.Execute(&buf, data); err != nil {
+			t.Fatalf("%s: %v", template, err)
+		}
+
+		if diff := cmp.Diff(buf.String(), expected); diff != "" {
+			t.Errorf("%s: mismatch (-actual, +expected):\n%s", template, diff)
+		}
+	}
+
+	test(t, "{.items[*].id}", "1 2 3")
+	test(t, "{$.items[*].ID}", "1 2 3")
+	test(t, "{.items[0].status}", "success")
+	test(t, "id: {.items[0].id}", "id: 1")
+
+	// indexes and slices
+	test(t, "{.items[-1].status}", "cancelled")
+	test(t, "{.items[-3].status}", "success")
+	test(t, "{.items[1:].id}", "2 3")
+	test(t, "{.items[:2].id}", "1 2")
+	test(t, "{.items[0:1].id}", "1")
+	test(t, "{.items[-2:].id}", "2 3")
+	test(t, "{.items[:-1].id}", "1 2")
+	test(t, "{.items[1:1].id}", "")
+	test(t, "{.items[2:1].id}", "")
+	test(t, "{.items[5:].id}", "")
+	test(t, "{.items[-5:1].id}", "1")
+	test(t, "{.items[:].id}", "1 2 3")
+
+	// quoted keys
+	test(t, "{.items[0]['status']}", "success")
+	test(t, `{.items[0]["status"]}`, "success")
+	test(t, "{.items[0].meta['run.id']}", "r1")
+	test(t, "{['items'][1]['id']}", "2")
+
+	// wildcards
+	test(t, "{.items[0].meta.*}", "3 lab r1")
+	test(t, "{.items[0].meta[*]}", "3 lab r1")
+	test(t, "{.items[*].tags[*]}", "a b c")
+	test(t, "{.items[1].tags[*]}", "")
+	test(t, "{.items[0].id.*}", "")
+
+	// recursive descent
+	test(t, "{..owner}", "lab")
+	test(t, "{..id}", "1 2 3")
+	test(t, "{.items[0]..status}", "success")
+	test(t, "{..tags[0]}", "a c")
+	test(t, "{..meta.*}", "3 lab r1")
+
+	// values
+	test(t, "{.items[0].tags}", `["a","b"]`)
+	test(t, "{.items[0].meta}", `{"count":3,"owner":"lab","run.id":"r1"}`)
+	test(t, "{.items[0].meta.count}", "3")
+	test(t, "{.}", `{"items":[{"id":1,"meta":{"count":3,"owner":"lab","run.id":"r1"},"status":"success","tags":["a","b"]},{"id":2,"status":"failed","tags":[]},{"id":3,"status":"cancelled","tags":["c"]}]}`)
+
+	// literals and ranges
+	test(t, `{"{"}{.items[0].id}{"}"}`, "{1}")
+	test(t, `{range .items[*]}{.id}{"\t"}{.status}{"\n"}{end}`, "1\tsuccess\n2\tfailed\n3\tcancelled\n")
+	test(t, `{range .items[*]}{range .tags[*]}{.}{","}{end}{end}`, "a,b,c,")
+	test(t, `{range .items[5:]}{.id}{end}`, "")
+}
+
+func TestJSONPathWithInvalidData(t *testing.T) {
+	data, err := toJSONValue(map[string]any{"id": 1, "items": []int{1, 2}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, template := range []string{
+		"{.name}",
+		"{.id.name}",
+		"{.items[2]}",
+		"{.items[-3]}",
+		"{.items.name}",
+		"{.id[0]}",
+		"{.id[0:1]}",
+		"{range .name}{end}",
+	} {
+		path, err := ParseJSONPath(template)
+
+		if err != nil {
+			t.Fatalf("%s: %v", template, err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := path.Execute(&buf, data); err == nil {
+			t.Errorf("%s: expected error", template)
+		}
+	}
+}
+
+func TestParseJSONPathWithInvalidTemplate(t *testing.T) {
+	for _, template := range []string{
+		"{.items",
+		"{.items[0}",
+		"{.items[}",
+		"{.items[]}",
+		"{.items[x]}",
+		"{.items[1:x]}",
+		"{.items[1:2:3]}",
+		"{.items[0]x}",
+		"{.items.}",
+		"{.items..}",
+		"{...id}",
+		"{items}",
+		"{$items}",
+		"{range .items[*]}{.id}",
+		"{range}",
+		"{end}",
+		`{"unterminated}`,
+		`{"\q"}`,
+	} {
+		if _, err := ParseJSONPath(template); err == nil {
+			t.Errorf("%s: expected error", template)
+		}
+	}
+}
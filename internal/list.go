@@ -0,0 +1,504 @@
+package internal
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+// defaultOrderBy is the order of `FetchWorkflows`, oldest first.
+const defaultOrderBy = "CreatedDate asc"
+
+type WorkflowSortKey string
+
+const (
+	WorkflowSortKeyCreated  = "created"
+	WorkflowSortKeyEnded    = "ended"
+	WorkflowSortKeyStatus   = "status"
+	WorkflowSortKeyDuration = "duration"
+	WorkflowSortKeyBases    = "bases"
+)
+
+var WorkflowSortKeyNames = []string{
+	WorkflowSortKeyCreated,
+	WorkflowSortKeyEnded,
+	WorkflowSortKeyStatus,
+	WorkflowSortKeyDuration,
+	WorkflowSortKeyBases,
+}
+
+func ParseWorkflowSortKey(s string) (WorkflowSortKey, error) {
+	switch s {
+	case "created":
+		return WorkflowSortKeyCreated, nil
+	case "ended":
+		return WorkflowSortKeyEnded, nil
+	case "status":
+		return WorkflowSortKeyStatus, nil
+	case "duration":
+		return WorkflowSortKeyDuration, nil
+	case "bases":
+		return WorkflowSortKeyBases, nil
+	default:
+		return "", fmt.Errorf("invalid sort key: %q", s)
+	}
+}
+
+// orderByField returns the field the API sorts by for the key, if any. The
+// duration is computed, so it is sorted locally.
+func (k WorkflowSortKey) orderByField() (string, bool) {
+	switch k {
+	case WorkflowSortKeyCreated:
+		return "CreatedDate", true
+	case WorkflowSortKeyEnded:
+		return "EndDate", true
+	case WorkflowSortKeyStatus:
+		return "Status", true
+	case WorkflowSortKeyBases:
+		return "BasesProcessed", true
+	default:
+		return "", false
+	}
+}
+
+// orderBy returns the OData `$orderby` expression for the key. Ties are
+// broken by ID, so pages are stable, except for creation dates, which are
+// practically unique.
+func (k WorkflowSortKey) orderBy(descending bool) (string, bool) {
+	field, ok := k.orderByField()
+
+	if !ok {
+		return "", false
+	}
+
+	direction := "asc"
+
+	if descending {
+		direction = "desc"
+	}
+
+	if k == WorkflowSortKeyCreated {
+		return fmt.Sprintf("%s %s", field, direction), true
+	}
+
+	return fmt.Sprintf("%s %s,Id %s", field, direction, direction), true
+}
+
+// compare orders workflows by the key, then by ID. Workflows without an end
+// date are ordered first by end date, as by the API.
+func (k WorkflowSortKey) compare(a *Workflow, b *Workflow) int {
+	var c int
+
+	switch k {
+	case WorkflowSortKeyEnded:
+		switch {
+		case a.EndDate == nil && b.EndDate == nil:
+			c = 0
+		case a.EndDate == nil:
+			c = -1
+		case b.EndDate == nil:
+			c = 1
+		default:
+			c = a.EndDate.Compare(*b.EndDate)
+		}
+	case WorkflowSortKeyStatus:
+		c = cmp.Compare(a.Status, b.Status)
+	case WorkflowSortKeyDuration:
+		c = cmp.Compare(a.Duration(), b.Duration())
+	case WorkflowSortKeyBases:
+		c = cmp.Compare(a.BasesProcessed, b.BasesProcessed)
+	default:
+		c = a.CreatedDate.Compare(b.CreatedDate)
+	}
+
+	if c != 0 {
+		return c
+	}
+
+	return cmp.Compare(a.ID, b.ID)
+}
+
+// SortWorkflows sorts the workflows by the key, in descending order if
+// reverse is set.
+func SortWorkflows(workflows []Workflow, key WorkflowSortKey, reverse bool) {
+	slices.SortStableFunc(workflows, func(a Workflow, b Workflow) int {
+		c := key.compare(&a, &b)
+
+		if reverse {
+			return -c
+		}
+
+		return c
+	})
+}
+
+// WorkflowListOptions selects, orders, and limits listed workflows.
+type WorkflowListOptions struct {
+	Filter WorkflowFilter
+
+	// Sort is the sort key. The default is the creation date.
+	Sort WorkflowSortKey
+
+	// Reverse sorts in descending order.
+	Reverse bool
+
+	// Limit keeps only the first workflows, and Last keeps only the last
+	// workflows, in the sort order. At most one can be set.
+	Limit int
+	Last  int
+
+	// PageSize is the number of workflows requested at once with `$top` and
+	// `$skip`. If 0, all workflows are requested at once.
+	PageSize int
+}
+
+func (o *WorkflowListOptions) validate() error {
+	switch {
+	case o.Limit < 0 || o.Last < 0 || o.PageSize < 0:
+		return errors.New("limit, last, and page size must not be negative")
+	case o.Limit > 0 && o.Last > 0:
+		return errors.New("limit and last cannot be used together")
+	default:
+		return nil
+	}
+}
+
+// WorkflowListOptionsFromFlags builds list options from the flags `--sort`,
+// `--reverse`, `--limit`, `--last`, and `--page-size`. The filter is not set
+// (see `WorkflowFilterFromFlags`).
+func WorkflowListOptionsFromFlags(flags *pflag.FlagSet) (WorkflowListOptions, error) {
+	options := WorkflowListOptions{}
+
+	rawSort, err := flags.GetString("sort")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Sort, err = ParseWorkflowSortKey(rawSort)
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Reverse, err = flags.GetBool("reverse")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Limit, err = flags.GetInt("limit")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Last, err = flags.GetInt("last")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.PageSize, err = flags.GetInt("page-size")
+
+	if err != nil {
+		return options, err
+	}
+
+	return options, options.validate()
+}
+
+func ListWorkflows(client Client, options WorkflowListOptions) ([]Workflow, error) {
+	return ListWorkflowsContext(context.Background(), client, options)
+}
+
+// ListWorkflowsContext is like `ListWorkflows` but stops when the context is
+// done.
+func ListWorkflowsContext(ctx context.Context, client Client, options WorkflowListOptions) ([]Workflow, error) {
+	workflows := []Workflow{}
+
+	for workflow, err := range IterWorkflowsContext(ctx, client, options) {
+		if err != nil {
+			return workflows, err
+		}
+
+		workflows = append(workflows, workflow)
+	}
+
+	return workflows, nil
+}
+
+func IterWorkflows(client Client, options WorkflowListOptions) iter.Seq2[Workflow, error] {
+	return IterWorkflowsContext(context.Background(), client, options)
+}
+
+// IterWorkflowsContext returns an iterator over the workflows selected by the
+// options. Iteration stops after the first error.
+//
+// As far as they can be expressed, the filter, order, and limit are sent to
+// the API as OData query options, and responses are decoded as they are read,
+// one workflow at a time. With a page size, workflows are requested in pages,
+// and the next page is requested only if iteration continues. If the API
+// rejects the query, all workflows are fetched with the default order and
+// selected locally, with the same result.
+//
+// Before the first workflow is returned, all selected workflows are read if
+// sorted by duration, and the last workflows are read if `Last` is set.
+func IterWorkflowsContext(ctx context.Context, client Client, options WorkflowListOptions) iter.Seq2[Workflow, error] {
+	return func(yield func(Workflow, error) bool) {
+		if err := options.validate(); err != nil {
+			yield(Workflow{}, err)
+			return
+		}
+
+		key := options.Sort
+
+		if len(key) == 0 {
+			key = WorkflowSortKeyCreated
+		}
+
+		// The last workflows are the first in the opposite order.
+		descending := options.Reverse != (options.Last > 0)
+		limit := max(options.Limit, options.Last)
+
+		orderBy, isServerSorted := key.orderBy(descending)
+
+		query := workflowQuery{
+			filter:   options.Filter,
+			orderBy:  orderBy,
+			pageSize: options.PageSize,
+			limit:    limit,
+		}
+
+		if !isServerSorted {
+			query.orderBy = defaultOrderBy
+			query.limit = 0
+		}
+
+		// Workflows are returned as they are read only if the API order is
+		// the final order.
+		isStreamed := isServerSorted && options.Last == 0
+		workflows := []Workflow{}
+
+		rejected, err := query.run(ctx, client, func(workflow Workflow) bool {
+			if isStreamed {
+				return yield(workflow, nil)
+			}
+
+			workflows = append(workflows, workflow)
+
+			return true
+		})
+
+		if err != nil {
+			yield(Workflow{}, err)
+			return
+		}
+
+		if rejected {
+			workflows, err = fetchWorkflows(ctx, client, workflowsEndpoint(defaultOrderBy, "", 0, 0))
+
+			if err != nil {
+				yield(Workflow{}, err)
+				return
+			}
+
+			workflows = options.Filter.Select(workflows)
+		} else if isStreamed {
+			return
+		}
+
+		SortWorkflows(workflows, key, descending)
+
+		if limit > 0 && len(workflows) > limit {
+			workflows = workflows[:limit]
+		}
+
+		if options.Last > 0 {
+			slices.Reverse(workflows)
+		}
+
+		for _, workflow := range workflows {
+			if !yield(workflow, nil) {
+				return
+			}
+		}
+	}
+}
+
+// workflowQuery is a listing of workflows with OData query options.
+type workflowQuery struct {
+	filter   WorkflowFilter
+	orderBy  string
+	pageSize int
+
+	// limit stops the query after the given number of selected workflows.
+	limit int
+}
+
+// run requests the workflows and calls fn with each workflow selected by the
+// filter until fn returns false or the limit is reached.
+//
+// If the API rejects the first request with HTTP 400, e.g., because it does
+// not support a query option, run returns true, and fn is not called. It also
+// returns true if the API ignores `$skip` before any workflow is selected.
+func (q *workflowQuery) run(ctx context.Context, client Client, fn func(Workflow) bool) (bool, error) {
+	expression := q.filter.odata()
+	pageSize := q.pageSize
+
+	// The first workflows returned are the result.
+	if pageSize == 0 && q.limit > 0 && q.filter.isODataComplete() {
+		pageSize = q.limit
+	}
+
+	selected := 0
+	stopped := false
+
+	var previousFirstID WorkflowID
+
+	for skip := 0; ; skip += pageSize {
+		endpoint := workflowsEndpoint(q.orderBy, expression, pageSize, skip)
+
+		var firstID WorkflowID
+		repeated := false
+
+		n, err := fetchWorkflowPage(ctx, client, endpoint, func(workflow Workflow) bool {
+			if firstID == 0 {
+				firstID = workflow.ID
+
+				// The API ignored `$skip` and returned the previous page
+				// again.
+				if skip > 0 && firstID == previousFirstID {
+					repeated = true
+					return false
+				}
+			}
+
+			if !q.filter.Matches(&workflow) {
+				return true
+			}
+
+			selected++
+			stopped = !fn(workflow) || (q.limit > 0 && selected >= q.limit)
+
+			return !stopped
+		})
+
+		var apiErr *APIError
+
+		if skip == 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusBadRequest {
+			slog.Info("query rejected by the API; selecting workflows locally", "endpoint", endpoint, "error", err)
+			return true, nil
+		}
+
+		if err != nil {
+			return false, err
+		}
+
+		if repeated {
+			if selected > 0 {
+				return false, fmt.Errorf("paging ignored by the API: %s: retry without a page size", endpoint)
+			}
+
+			slog.Info("paging ignored by the API; selecting workflows locally", "endpoint", endpoint)
+			return true, nil
+		}
+
+		previousFirstID = firstID
+
+		// A page larger than requested means the API ignored `$top` and
+		// returned all workflows.
+		if stopped || pageSize == 0 || n != pageSize {
+			return false, nil
+		}
+	}
+}
+
+// workflowsEndpoint returns the endpoint to list workflows with the given
+// OData query options. Empty or zero options are omitted.
+func workflowsEndpoint(orderBy string, filter string, top int, skip int) string {
+	query := []string{"$orderby=" + escapeODataQuery(orderBy)}
+
+	if len(filter) > 0 {
+		query = append(query, "$filter="+escapeODataQuery(filter))
+	}
+
+	if top > 0 {
+		query = append(query, fmt.Sprintf("$top=%d", top))
+	}
+
+	if skip > 0 {
+		query = append(query, fmt.Sprintf("$skip=%d", skip))
+	}
+
+	return "/api/workflows?" + strings.Join(query, "&")
+}
+
+// escapeODataQuery escapes a query option value, with spaces as `%20`.
+func escapeODataQuery(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func fetchWorkflows(ctx context.Context, client Client, endpoint string) ([]Workflow, error) {
+	workflows := []Workflow{}
+
+	_, err := fetchWorkflowPage(ctx, client, endpoint, func(workflow Workflow) bool {
+		workflows = append(workflows, workflow)
+		return true
+	})
+
+	return workflows, err
+}
+
+// fetchWorkflowPage requests a JSON array of workflows and calls fn with each
+// workflow as it is decoded, until fn returns false. It returns the number of
+// workflows decoded.
+func fetchWorkflowPage(ctx context.Context, client Client, endpoint string, fn func(Workflow) bool) (int, error) {
+	response, err := client.GetContext(ctx, endpoint)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer response.Body.Close()
+
+	decoder := json.NewDecoder(response.Body)
+
+	if token, err := decoder.Token(); err != nil {
+		return 0, err
+	} else if token != json.Delim('[') {
+		return 0, fmt.Errorf("invalid workflows response: expected an array, got %v", token)
+	}
+
+	n := 0
+
+	for decoder.More() {
+		workflow := Workflow{}
+
+		if err := decoder.Decode(&workflow); err != nil {
+			return n, err
+		}
+
+		n++
+
+		if !fn(workflow) {
+			return n, nil
+		}
+	}
+
+	if _, err := decoder.Token(); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// newListTestServer returns a server with workflows 1 to 5, created a day
+// apart, where workflow n has n bases processed and runs for 6 - n hours. It
+// supports `$orderby` by creation date or bases, `$top`, and `$skip`, unless
+// paging is unsupported, in which case `$top` is rejected. The requested
+// query strings are recorded.
+func newListTestServer(t testing.TB, pagingSupported bool) (*httptest.Server, func() []string) {
+	t.Helper()
+
+	workflows := []Workflow{}
+
+	for i := 1; i <= 5; i++ {
+		createdDate := time.Date(2024, 1, i, 0, 0, 0, 0, time.UTC)
+		endDate := createdDate.Add(time.Duration(6-i) * time.Hour)
+
+		workflows = append(workflows, Workflow{
+			ID:             WorkflowID(i),
+			Status:         StatusSuccess,
+			CreatedDate:    createdDate,
+			EndDate:        &endDate,
+			BasesProcessed: uint64(i),
+		})
+	}
+
+	var mu sync.Mutex
+	queries := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		queries = append(queries, r.URL.RawQuery)
+		mu.Unlock()
+
+		query := r.URL.Query()
+		page := slices.Clone(workflows)
+
+		switch query.Get("$orderby") {
+		case "CreatedDate asc", "BasesProcessed asc,Id asc":
+		case "CreatedDate desc", "BasesProcessed desc,Id desc":
+			slices.Reverse(page)
+		default:
+			t.Errorf("unexpected $orderby: %q", query.Get("$orderby"))
+		}
+
+		if query.Has("$top") && !pagingSupported {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if skip, err := strconv.Atoi(query.Get("$skip")); err == nil {
+			page = page[min(skip, len(page)):]
+		}
+
+		if top, err := strconv.Atoi(query.Get("$top")); err == nil {
+			page = page[:min(top, len(page))]
+		}
+
+		json.NewEncoder(rw).Encode(page)
+	}))
+
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(queries)
+	}
+}
+
+func TestListWorkflows(t *testing.T) {
+	test := func(
+		t testing.TB,
+		pagingSupported bool,
+		options WorkflowListOptions,
+		expectedIDs []WorkflowID,
+		expectedQueries []string,
+	) {
+		t.Helper()
+
+		server, queries := newListTestServer(t, pagingSupported)
+		defer server.Close()
+
+		client := NewClient(server.URL, "secret")
+		workflows, err := ListWorkflowsContext(context.Background(), client, options)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ids := []WorkflowID{}
+
+		for _, workflow := range workflows {
+			ids = append(ids, workflow.ID)
+		}
+
+		if diff := cmp.Diff(ids, expectedIDs); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+
+		if diff := cmp.Diff(queries(), expectedQueries); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+	}
+
+	test(
+		t,
+		true,
+		WorkflowListOptions{},
+		[]WorkflowID{1, 2, 3, 4, 5},
+		[]string{"$orderby=CreatedDate%20asc"},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{PageSize: 2},
+		[]WorkflowID{1, 2, 3, 4, 5},
+		[]string{
+			"$orderby=CreatedDate%20asc&$top=2",
+			"$orderby=CreatedDate%20asc&$top=2&$skip=2",
+			"$orderby=CreatedDate%20asc&$top=2&$skip=4",
+		},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{Limit: 2},
+		[]WorkflowID{1, 2},
+		[]string{"$orderby=CreatedDate%20asc&$top=2"},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{Last: 2},
+		[]WorkflowID{4, 5},
+		[]string{"$orderby=CreatedDate%20desc&$top=2"},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{Sort: WorkflowSortKeyBases, Reverse: true, Limit: 2},
+		[]WorkflowID{5, 4},
+		[]string{"$orderby=BasesProcessed%20desc%2CId%20desc&$top=2"},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{Sort: WorkflowSortKeyDuration, Limit: 2, PageSize: 3},
+		[]WorkflowID{5, 4},
+		[]string{
+			"$orderby=CreatedDate%20asc&$top=3",
+			"$orderby=CreatedDate%20asc&$top=3&$skip=3",
+		},
+	)
+	test(
+		t,
+		true,
+		WorkflowListOptions{Filter: WorkflowFilter{Description: mustParsePattern(t, "/x/")}, Limit: 1},
+		[]WorkflowID{},
+		[]string{"$orderby=CreatedDate%20asc"},
+	)
+	test(
+		t,
+		false,
+		WorkflowListOptions{Last: 3, Reverse: true},
+		[]WorkflowID{3, 2, 1},
+		[]string{"$orderby=CreatedDate%20asc&$top=3", "$orderby=CreatedDate%20asc"},
+	)
+}
+
+func TestIterWorkflowsStopsRequesting(t *testing.T) {
+	server, queries := newListTestServer(t, true)
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	for workflow, err := range IterWorkflowsContext(context.Background(), client, WorkflowListOptions{PageSize: 2}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if workflow.ID == 3 {
+			break
+		}
+	}
+
+	if n := len(queries()); n != 2 {
+		t.Errorf("expected 2 requests, got %d", n)
+	}
+}
+
+func TestIterWorkflowsWithIgnoredTop(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("$skip") {
+			t.Error("unexpected request for a second page")
+		}
+
+		rw.Write([]byte(`[{"Id":1},{"Id":2},{"Id":3}]`))
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	workflows, err := ListWorkflowsContext(context.Background(), client, WorkflowListOptions{PageSize: 2})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workflows) != 3 {
+		t.Errorf("expected 3 workflows, got %d", len(workflows))
+	}
+}
+
+func TestIterWorkflowsWithIgnoredSkip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has("$top") {
+			rw.Write([]byte(`[{"Id":1}]`))
+			return
+		}
+
+		rw.Write([]byte(`[{"Id":1},{"Id":2},{"Id":3}]`))
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	options := WorkflowListOptions{Filter: WorkflowFilter{MinID: 3}, Limit: 1}
+	workflows, err := ListWorkflowsContext(context.Background(), client, options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(workflows) != 1 || workflows[0].ID != 3 {
+		t.Errorf("expected workflow 3, got %+v", workflows)
+	}
+}
+
+func TestSortWorkflows(t *testing.T) {
+	test := func(t testing.TB, key WorkflowSortKey, reverse bool, expectedIDs []WorkflowID) {
+		t.Helper()
+
+		workflows := newTestWorkflows()
+		workflows = append(workflows, Workflow{
+			ID:          3,
+			Status:      StatusWorking,
+			CreatedDate: time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC),
+		})
+
+		SortWorkflows(workflows, key, reverse)
+
+		ids := []WorkflowID{}
+
+		for _, workflow := range workflows {
+			ids = append(ids, workflow.ID)
+		}
+
+		if diff := cmp.Diff(ids, expectedIDs); diff != "" {
+			t.Errorf("%s: mismatch (-actual, +expected):\n%s", key, diff)
+		}
+	}
+
+	test(t, WorkflowSortKeyCreated, false, []WorkflowID{1, 2, 3})
+	test(t, WorkflowSortKeyCreated, true, []WorkflowID{3, 2, 1})
+	test(t, WorkflowSortKeyEnded, false, []WorkflowID{3, 1, 2})
+	test(t, WorkflowSortKeyStatus, false, []WorkflowID{3, 1, 2})
+	test(t, WorkflowSortKeyDuration, false, []WorkflowID{2, 1, 3})
+	test(t, WorkflowSortKeyBases, true, []WorkflowID{1, 3, 2})
+}
+
+func TestParseWorkflowSortKey(t *testing.T) {
+	for _, name := range WorkflowSortKeyNames {
+		if _, err := ParseWorkflowSortKey(name); err != nil {
+			t.Errorf("unexpected error for %q: %v", name, err)
+		}
+	}
+
+	if _, err := ParseWorkflowSortKey("id"); err == nil {
+		t.Error("expected error for an invalid sort key")
+	}
+}
+
+func TestWorkflowListOptionsValidate(t *testing.T) {
+	for _, options := range []WorkflowListOptions{
+		{Limit: 1, Last: 1},
+		{Limit: -1},
+		{PageSize: -1},
+	} {
+		if err := options.validate(); err == nil {
+			t.Errorf("%+v: expected error", options)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+
+	"github.com/spf13/pflag"
+)
+
+type LogLevel string
+
+const (
+	LogLevelDebug = "debug"
+	LogLevelInfo  = "info"
+	LogLevelWarn  = "warn"
+	LogLevelError = "error"
+	LogLevelOff   = "off"
+)
+
+// logLevelOff is above every level, so no record is enabled.
+const logLevelOff = slog.Level(math.MaxInt32)
+
+func ParseLogLevel(s string) (LogLevel, error) {
+	switch s {
+	case "debug":
+		return LogLevelDebug, nil
+	case "info":
+		return LogLevelInfo, nil
+	case "warn":
+		return LogLevelWarn, nil
+	case "error":
+		return LogLevelError, nil
+	case "off":
+		return LogLevelOff, nil
+	default:
+		return "", fmt.Errorf("invalid log level: %q", s)
+	}
+}
+
+func (l LogLevel) slogLevel() slog.Level {
+	switch l {
+	case LogLevelDebug:
+		return slog.LevelDebug
+	case LogLevelInfo:
+		return slog.LevelInfo
+	case LogLevelWarn:
+		return slog.LevelWarn
+	case LogLevelError:
+		return slog.LevelError
+	default:
+		return logLevelOff
+	}
+}
+
+type LogFormat string
+
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch s {
+	case "text":
+		return LogFormatText, nil
+	case "json":
+		return LogFormatJSON, nil
+	default:
+		return "", fmt.Errorf("invalid log format: %q", s)
+	}
+}
+
+// LogOptions configures the logger.
+type LogOptions struct {
+	Level  LogLevel
+	Format LogFormat
+	// File is the path of the file to append logs to. If empty, logs are
+	// written to stderr.
+	File string
+}
+
+// LogOptionsFromFlags returns the log options from the flags. If `log-level`
+// is empty, the given default level is used.
+func LogOptionsFromFlags(flags *pflag.FlagSet, defaultLevel LogLevel) (LogOptions, error) {
+	options := LogOptions{}
+
+	rawLevel, err := flags.GetString("log-level")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Level = defaultLevel
+
+	if len(rawLevel) > 0 {
+		level, err := ParseLogLevel(rawLevel)
+
+		if err != nil {
+			return options, err
+		}
+
+		options.Level = level
+	}
+
+	rawFormat, err := flags.GetString("log-format")
+
+	if err != nil {
+		return options, err
+	}
+
+	format, err := ParseLogFormat(rawFormat)
+
+	if err != nil {
+		return options, err
+	}
+
+	options.Format = format
+
+	file, err := flags.GetString("log-file")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.File = file
+
+	return options, nil
+}
+
+// NewLogger returns a logger with the options. Secrets are redacted (see
+// `RedactingHandler`).
+//
+// The returned closer closes the log file, if any.
+func NewLogger(options LogOptions) (*slog.Logger, io.Closer, error) {
+	var w io.Writer = os.Stderr
+	var closer io.Closer = io.NopCloser(nil)
+
+	if len(options.File) > 0 {
+		f, err := os.OpenFile(options.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("could not open log file: %w", err)
+		}
+
+		w = f
+		closer = f
+	}
+
+	return newLogger(w, options), closer, nil
+}
+
+func newLogger(w io.Writer, options LogOptions) *slog.Logger {
+	handlerOptions := &slog.HandlerOptions{Level: options.Level.slogLevel()}
+
+	var handler slog.Handler
+
+	switch options.Format {
+	case LogFormatJSON:
+		handler = slog.NewJSONHandler(w, handlerOptions)
+	default:
+		handler = slog.NewTextHandler(w, handlerOptions)
+	}
+
+	return slog.New(NewRedactingHandler(handler))
+}
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func TestParseLogLevel(t *testing.T) {
+	for _, s := range []string{"debug", "info", "warn", "error", "off"} {
+		if _, err := ParseLogLevel(s); err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		}
+	}
+
+	if _, err := ParseLogLevel("verbose"); err == nil {
+		t.Error("expected error for an invalid log level")
+	}
+}
+
+func TestNewLoggerFiltersByLevel(t *testing.T) {
+	test := func(t testing.TB, level LogLevel, expected []string) {
+		t.Helper()
+
+		var buf bytes.Buffer
+		logger := newLogger(&buf, LogOptions{Level: level, Format: LogFormatText})
+
+		logger.Debug("debug")
+		logger.Info("info")
+		logger.Warn("warn")
+		logger.Error("error")
+
+		actual := []string{}
+
+		for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+			if _, message, ok := strings.Cut(line, "msg="); ok {
+				actual = append(actual, message)
+			}
+		}
+
+		if diff := cmp.Diff(actual, expected); diff != "" {
+			t.Errorf("level %s: mismatch (-actual, +expected):\n%s", level, diff)
+		}
+	}
+
+	test(t, LogLevelDebug, []string{"debug", "info", "warn", "error"})
+	test(t, LogLevelInfo, []string{"info", "warn", "error"})
+	test(t, LogLevelWarn, []string{"warn", "error"})
+	test(t, LogLevelError, []string{"error"})
+	test(t, LogLevelOff, []string{})
+}
+
+func TestNewLoggerWithJSONFormat(t *testing.T) {
+	RegisterSecret(testAccessKey)
+
+	var buf bytes.Buffer
+	logger := newLogger(&buf, LogOptions{Level: LogLevelInfo, Format: LogFormatJSON})
+
+	logger.Info("request", "accessKey", testAccessKey)
+
+	var record map[string]any
+
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("invalid JSON log record: %v: %s", err, buf.String())
+	}
+
+	if record["msg"] != "request" {
+		t.Errorf("expected msg %q, got %v", "request", record["msg"])
+	}
+
+	if record["accessKey"] != secretMask {
+		t.Errorf("expected accessKey to be masked, got %v", record["accessKey"])
+	}
+}
+
+func TestNewLoggerWithFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "msgenctl.log")
+
+	if err := os.WriteFile(path, []byte("existing\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, closer, err := NewLogger(LogOptions{Level: LogLevelInfo, Format: LogFormatText, File: path})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger.Info("status")
+
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+
+	if len(lines) != 2 || lines[0] != "existing" || !strings.Contains(lines[1], "msg=status") {
+		t.Errorf("expected the record to be appended, got:\n%s", data)
+	}
+}
+
+func TestLogOptionsFromFlags(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("msgenctl", pflag.ContinueOnError)
+		flags.String("log-level", "", "")
+		flags.String("log-format", LogFormatText, "")
+		flags.String("log-file", "", "")
+		return flags
+	}
+
+	flags := newFlags()
+	options, err := LogOptionsFromFlags(flags, LogLevelWarn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := LogOptions{Level: LogLevelWarn, Format: LogFormatText}
+
+	if diff := cmp.Diff(options, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	flags = newFlags()
+	flags.Set("log-level", "debug")
+	flags.Set("log-format", "json")
+	flags.Set("log-file", "msgenctl.log")
+
+	options, err = LogOptionsFromFlags(flags, LogLevelWarn)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = LogOptions{Level: LogLevelDebug, Format: LogFormatJSON, File: "msgenctl.log"}
+
+	if diff := cmp.Diff(options, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	flags = newFlags()
+	flags.Set("log-format", "xml")
+
+	if _, err := LogOptionsFromFlags(flags, LogLevelWarn); err == nil {
+		t.Error("expected error for an invalid log format")
+	}
+}
@@ -0,0 +1,493 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+type OutputFormat string
+
+const (
+	// OutputFormatDetail is the human-readable layout of each field, one per
+	// line.
+	OutputFormatDetail = "detail"
+	// OutputFormatTable is a compact table, one row per workflow.
+	OutputFormatTable = "table"
+	// OutputFormatWide is a table of all fields, one row per workflow.
+	OutputFormatWide  = "wide"
+	OutputFormatJSON  = "json"
+	OutputFormatJSONL = "jsonl"
+	OutputFormatYAML  = "yaml"
+	OutputFormatCSV   = "csv"
+	OutputFormatTSV   = "tsv"
+)
+
+// OutputFormatNames are the names of the output formats, for usage messages.
+var OutputFormatNames = []string{
+	OutputFormatDetail,
+	OutputFormatTable,
+	OutputFormatWide,
+	OutputFormatJSON,
+	OutputFormatJSONL,
+	OutputFormatYAML,
+	OutputFormatCSV,
+	OutputFormatTSV,
+}
+
+func ParseOutputFormat(s string) (OutputFormat, error) {
+	switch s {
+	case "detail":
+		return OutputFormatDetail, nil
+	case "table":
+		return OutputFormatTable, nil
+	case "wide":
+		return OutputFormatWide, nil
+	case "json":
+		return OutputFormatJSON, nil
+	case "jsonl":
+		return OutputFormatJSONL, nil
+	case "yaml":
+		return OutputFormatYAML, nil
+	case "csv":
+		return OutputFormatCSV, nil
+	case "tsv":
+		return OutputFormatTSV, nil
+	default:
+		return "", fmt.Errorf("invalid output format: %q", s)
+	}
+}
+
+// OutputOptions selects how workflows are written: in a format, with a Go
+// template (see `NewWorkflowTemplate`), or with a JSONPath template (see
+// `JSONPath`). At most one may be set.
+type OutputOptions struct {
+	Format   OutputFormat
+	Template string
+	JSONPath string
+
+	// FailureCodes explain failures in the detail format, in addition to the
+	// built-in catalog (see `ExplainFailure`).
+	FailureCodes FailureCodes
+}
+
+// IsZero returns whether no output option is set, i.e., the command default
+// applies.
+func (o OutputOptions) IsZero() bool {
+	return len(o.Format) == 0 && len(o.Template) == 0 && len(o.JSONPath) == 0
+}
+
+// WorkflowWriter writes workflows with the output options.
+type WorkflowWriter struct {
+	format       OutputFormat
+	template     *template.Template
+	jsonPath     *JSONPath
+	failureCodes FailureCodes
+}
+
+// NewWorkflowWriter returns a writer for the output options. An empty format
+// defaults to `OutputFormatDetail`.
+func NewWorkflowWriter(options OutputOptions) (*WorkflowWriter, error) {
+	set := 0
+
+	for _, option := range []string{string(options.Format), options.Template, options.JSONPath} {
+		if len(option) > 0 {
+			set++
+		}
+	}
+
+	if set > 1 {
+		return nil, errors.New("at most one of output format, template, or JSONPath can be given")
+	}
+
+	writer := &WorkflowWriter{format: options.Format, failureCodes: options.FailureCodes}
+
+	if len(writer.format) == 0 {
+		writer.format = OutputFormatDetail
+	}
+
+	if len(options.Template) > 0 {
+		tmpl, err := NewWorkflowTemplate(options.Template)
+
+		if err != nil {
+			return nil, err
+		}
+
+		writer.template = tmpl
+	}
+
+	if len(options.JSONPath) > 0 {
+		jsonPath, err := ParseJSONPath(options.JSONPath)
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSONPath: %w", err)
+		}
+
+		writer.jsonPath = jsonPath
+	}
+
+	return writer, nil
+}
+
+// WriteWorkflow writes a single workflow. See `WriteWorkflow`.
+//
+// A JSONPath template is executed with the workflow as an object of the
+// fields of `WorkflowRecord`.
+func (w *WorkflowWriter) WriteWorkflow(out io.Writer, workflow Workflow) error {
+	switch {
+	case w.template != nil:
+		return w.executeTemplate(out, workflow)
+	case w.jsonPath != nil:
+		return w.executeJSONPath(out, NewWorkflowRecord(workflow))
+	default:
+		return writeWorkflow(out, w.format, workflow, w.failureCodes)
+	}
+}
+
+// WriteWorkflows writes the workflows. See `WriteWorkflows`.
+//
+// A Go template is executed once per workflow. A JSONPath template is
+// executed once with an object with the workflows in `items`, e.g.,
+// `{.items[*].id}`.
+func (w *WorkflowWriter) WriteWorkflows(out io.Writer, workflows []Workflow) error {
+	switch {
+	case w.template != nil:
+		for _, workflow := range workflows {
+			if err := w.executeTemplate(out, workflow); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case w.jsonPath != nil:
+		records := make([]WorkflowRecord, len(workflows))
+
+		for i, workflow := range workflows {
+			records[i] = NewWorkflowRecord(workflow)
+		}
+
+		return w.executeJSONPath(out, map[string]any{"items": records})
+	default:
+		return writeWorkflows(out, w.format, workflows, w.failureCodes)
+	}
+}
+
+func (w *WorkflowWriter) executeTemplate(out io.Writer, workflow Workflow) error {
+	var buf bytes.Buffer
+
+	workflow.Description = WithoutSubmissionToken(workflow.Description)
+
+	// The workflow is passed by pointer for methods such as `Duration`.
+	if err := w.template.Execute(&buf, &workflow); err != nil {
+		return fmt.Errorf("could not execute template: %w", err)
+	}
+
+	return writeLine(out, buf.Bytes())
+}
+
+func (w *WorkflowWriter) executeJSONPath(out io.Writer, v any) error {
+	data, err := toJSONValue(v)
+
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+
+	if err := w.jsonPath.Execute(&buf, data); err != nil {
+		return fmt.Errorf("could not execute JSONPath: %w", err)
+	}
+
+	return writeLine(out, buf.Bytes())
+}
+
+// writeLine writes the data with a trailing newline, unless it is empty or
+// already ends with one.
+func writeLine(w io.Writer, data []byte) error {
+	if len(data) > 0 && !bytes.HasSuffix(data, []byte("\n")) {
+		data = append(data, '\n')
+	}
+
+	_, err := w.Write(data)
+
+	return err
+}
+
+// WorkflowRecord is the representation of a workflow in machine-readable
+// output formats. It includes all `Workflow` fields, with the submission
+// token split from the description (see `SubmissionToken`).
+type WorkflowRecord struct {
+	ID              WorkflowID `json:"id" yaml:"id"`
+	TenantID        int        `json:"tenantId" yaml:"tenantId"`
+	Status          string     `json:"status" yaml:"status"`
+	StatusCode      Status     `json:"statusCode" yaml:"statusCode"`
+	FailureCode     int        `json:"failureCode" yaml:"failureCode"`
+	Message         string     `json:"message" yaml:"message"`
+	Description     string     `json:"description" yaml:"description"`
+	SubmissionToken string     `json:"submissionToken" yaml:"submissionToken"`
+	Process         string     `json:"process" yaml:"process"`
+	CreatedDate     time.Time  `json:"createdDate" yaml:"createdDate"`
+	EndDate         *time.Time `json:"endDate" yaml:"endDate"`
+	Duration        string     `json:"duration" yaml:"duration"`
+	BasesProcessed  uint64     `json:"basesProcessed" yaml:"basesProcessed"`
+}
+
+// workflowRecordColumns are the CSV and TSV columns, in the same order as the
+// fields of `WorkflowRecord`.
+var workflowRecordColumns = []string{
+	"id",
+	"tenantId",
+	"status",
+	"statusCode",
+	"failureCode",
+	"message",
+	"description",
+	"submissionToken",
+	"process",
+	"createdDate",
+	"endDate",
+	"duration",
+	"basesProcessed",
+}
+
+func NewWorkflowRecord(workflow Workflow) WorkflowRecord {
+	submissionToken, _ := SubmissionToken(workflow.Description)
+
+	return WorkflowRecord{
+		ID:              workflow.ID,
+		TenantID:        workflow.TenantID,
+		Status:          workflow.Status.String(),
+		StatusCode:      workflow.Status,
+		FailureCode:     workflow.FailureCode,
+		Message:         workflow.Message,
+		Description:     WithoutSubmissionToken(workflow.Description),
+		SubmissionToken: submissionToken,
+		Process:         workflow.Process,
+		CreatedDate:     workflow.CreatedDate,
+		EndDate:         workflow.EndDate,
+		Duration:        workflow.Duration().Round(time.Second).String(),
+		BasesProcessed:  workflow.BasesProcessed,
+	}
+}
+
+func (r *WorkflowRecord) values() []string {
+	endDate := ""
+
+	if r.EndDate != nil {
+		endDate = r.EndDate.Format(time.RFC3339)
+	}
+
+	return []string{
+		strconv.Itoa(int(r.ID)),
+		strconv.Itoa(r.TenantID),
+		r.Status,
+		strconv.Itoa(int(r.StatusCode)),
+		strconv.Itoa(r.FailureCode),
+		r.Message,
+		r.Description,
+		r.SubmissionToken,
+		r.Process,
+		r.CreatedDate.Format(time.RFC3339),
+		endDate,
+		r.Duration,
+		strconv.FormatUint(r.BasesProcessed, 10),
+	}
+}
+
+// WriteWorkflow writes a single workflow in the given format. Unlike
+// `WriteWorkflows`, JSON and YAML are written as an object rather than a
+// list.
+//
+// In the detail format, failures are explained with the built-in catalog
+// (see `ExplainFailure`).
+func WriteWorkflow(w io.Writer, format OutputFormat, workflow Workflow) error {
+	return writeWorkflow(w, format, workflow, nil)
+}
+
+func writeWorkflow(w io.Writer, format OutputFormat, workflow Workflow, failureCodes FailureCodes) error {
+	switch format {
+	case OutputFormatDetail:
+		return writeDetail(w, workflow, failureCodes)
+	case OutputFormatJSON:
+		return writeJSON(w, NewWorkflowRecord(workflow))
+	case OutputFormatYAML:
+		return writeYAML(w, NewWorkflowRecord(workflow))
+	default:
+		return writeWorkflows(w, format, []Workflow{workflow}, failureCodes)
+	}
+}
+
+// WriteWorkflows writes the workflows in the given format.
+func WriteWorkflows(w io.Writer, format OutputFormat, workflows []Workflow) error {
+	return writeWorkflows(w, format, workflows, nil)
+}
+
+func writeWorkflows(w io.Writer, format OutputFormat, workflows []Workflow, failureCodes FailureCodes) error {
+	records := make([]WorkflowRecord, len(workflows))
+
+	for i, workflow := range workflows {
+		records[i] = NewWorkflowRecord(workflow)
+	}
+
+	switch format {
+	case OutputFormatDetail:
+		for _, workflow := range workflows {
+			if err := writeDetail(w, workflow, failureCodes); err != nil {
+				return err
+			}
+
+			fmt.Fprintln(w)
+		}
+
+		return nil
+	case OutputFormatTable:
+		return writeTable(w, records, false)
+	case OutputFormatWide:
+		return writeTable(w, records, true)
+	case OutputFormatJSON:
+		return writeJSON(w, records)
+	case OutputFormatJSONL:
+		encoder := json.NewEncoder(w)
+
+		for _, record := range records {
+			if err := encoder.Encode(record); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case OutputFormatYAML:
+		return writeYAML(w, records)
+	case OutputFormatCSV:
+		return writeDelimited(w, records, ',')
+	case OutputFormatTSV:
+		return writeDelimited(w, records, '\t')
+	default:
+		return fmt.Errorf("invalid output format: %q", format)
+	}
+}
+
+// writeDetail writes each field of the workflow on its own line. The failure
+// code and its explanation, if any, are only written for failures.
+func writeDetail(w io.Writer, workflow Workflow, failureCodes FailureCodes) error {
+	fmt.Fprintf(w, "Workflow ID     : %v\n", workflow.ID)
+	fmt.Fprintf(w, "Status          : %s (%d)\n", workflow.Status, workflow.Status)
+
+	if workflow.FailureCode != 0 {
+		fmt.Fprintf(w, "Failure Code    : %d\n", workflow.FailureCode)
+	}
+
+	fmt.Fprintf(w, "Message         : %s\n", workflow.Message)
+
+	if info, ok := ExplainFailure(&workflow, failureCodes); ok {
+		if info.FromMessage {
+			fmt.Fprintf(w, "Explanation     : %s (guessed from the message)\n", info.Explanation)
+		} else {
+			fmt.Fprintf(w, "Explanation     : %s\n", info.Explanation)
+		}
+
+		if len(info.Cause) > 0 {
+			fmt.Fprintf(w, "Likely Cause    : %s\n", info.Cause)
+		}
+
+		if len(info.Fix) > 0 {
+			fmt.Fprintf(w, "Suggested Fix   : %s\n", info.Fix)
+		}
+	}
+
+	fmt.Fprintf(w, "Process         : %v\n", workflow.Process)
+	fmt.Fprintf(w, "Description     : %v\n", WithoutSubmissionToken(workflow.Description))
+	fmt.Fprintf(w, "Created Date    : %v\n", workflow.CreatedDate)
+	fmt.Fprintf(w, "End Date        : %v\n", workflow.EndDate)
+	fmt.Fprintf(w, "Wall Clock Time : %v\n", workflow.Duration())
+	_, err := fmt.Fprintf(w, "Bases Processed : %d\n", workflow.BasesProcessed)
+
+	return err
+}
+
+func writeTable(w io.Writer, records []WorkflowRecord, wide bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	if wide {
+		fmt.Fprintln(tw, "ID\tSTATUS\tFAILURE CODE\tCREATED\tENDED\tDURATION\tBASES\tPROCESS\tDESCRIPTION\tMESSAGE")
+	} else {
+		fmt.Fprintln(tw, "ID\tSTATUS\tCREATED\tDURATION\tDESCRIPTION")
+	}
+
+	for _, record := range records {
+		created := record.CreatedDate.Format(time.DateTime)
+
+		if !wide {
+			fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", record.ID, record.Status, created, record.Duration, record.Description)
+			continue
+		}
+
+		ended := "-"
+
+		if record.EndDate != nil {
+			ended = record.EndDate.Format(time.DateTime)
+		}
+
+		fmt.Fprintf(
+			tw,
+			"%d\t%s\t%d\t%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			record.ID,
+			record.Status,
+			record.FailureCode,
+			created,
+			ended,
+			record.Duration,
+			record.BasesProcessed,
+			record.Process,
+			record.Description,
+			record.Message,
+		)
+	}
+
+	return tw.Flush()
+}
+
+func writeJSON(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func writeYAML(w io.Writer, v any) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func writeDelimited(w io.Writer, records []WorkflowRecord, delimiter rune) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = delimiter
+
+	if err := writer.Write(workflowRecordColumns); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if err := writer.Write(record.values()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func newTestWorkflows() []Workflow {
+	endDate := time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC)
+	failedEndDate := time.Date(2024, 1, 2, 0, 10, 0, 0, time.UTC)
+
+	return []Workflow{
+		{
+			ID:             1,
+			TenantID:       7,
+			Status:         StatusSuccess,
+			CreatedDate:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			EndDate:        &endDate,
+			Description:    "sample, one [msgenctl-submission:0123456789abcdef01234567]",
+			Process:        "snapgatk-20230626_1",
+			BasesProcessed: 100,
+		},
+		{
+			ID:          2,
+			TenantID:    7,
+			Status:      StatusFailed,
+			CreatedDate: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			EndDate:     &failedEndDate,
+			FailureCode: 301,
+			Message:     "invalid input",
+		},
+	}
+}
+
+func TestParseOutputFormat(t *testing.T) {
+	for _, name := range OutputFormatNames {
+		if _, err := ParseOutputFormat(name); err != nil {
+			t.Errorf("unexpected error for %q: %v", name, err)
+		}
+	}
+
+	if _, err := ParseOutputFormat("xml"); err == nil {
+		t.Error("expected error for an invalid output format")
+	}
+}
+
+func TestWriteWorkflowsWithJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflows(&buf, OutputFormatJSON, newTestWorkflows()); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []map[string]any
+
+	if err := json.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	for _, key := range []string{"tenantId", "failureCode", "statusCode", "endDate"} {
+		if _, ok := records[1][key]; !ok {
+			t.Errorf("missing %q", key)
+		}
+	}
+
+	if records[1]["failureCode"] != float64(301) || records[1]["status"] != "failed" {
+		t.Errorf("unexpected record: %v", records[1])
+	}
+}
+
+func TestWriteWorkflowWithJSON(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflow(&buf, OutputFormatJSON, newTestWorkflows()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var record WorkflowRecord
+
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(record, NewWorkflowRecord(newTestWorkflows()[0])); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestWriteWorkflowsWithJSONL(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflows(&buf, OutputFormatJSONL, newTestWorkflows()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d", len(lines))
+	}
+
+	var record WorkflowRecord
+
+	if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	if record.ID != 2 || record.FailureCode != 301 || record.TenantID != 7 {
+		t.Errorf("unexpected record: %+v", record)
+	}
+}
+
+func TestWriteWorkflowsWithYAML(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflows(&buf, OutputFormatYAML, newTestWorkflows()); err != nil {
+		t.Fatal(err)
+	}
+
+	var records []WorkflowRecord
+
+	if err := yaml.Unmarshal(buf.Bytes(), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []WorkflowRecord{}
+
+	for _, workflow := range newTestWorkflows() {
+		expected = append(expected, NewWorkflowRecord(workflow))
+	}
+
+	if diff := cmp.Diff(records, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestWriteWorkflowsWithDelimited(t *testing.T) {
+	test := func(t testing.TB, format OutputFormat, delimiter rune) {
+		t.Helper()
+
+		var buf bytes.Buffer
+
+		if err := WriteWorkflows(&buf, format, newTestWorkflows()); err != nil {
+			t.Fatal(err)
+		}
+
+		reader := csv.NewReader(&buf)
+		reader.Comma = delimiter
+		rows, err := reader.ReadAll()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := [][]string{
+			workflowRecordColumns,
+			{"1", "7", "success", "20000", "0", "", "sample, one", "0123456789abcdef01234567", "snapgatk-20230626_1", "2024-01-01T00:00:00Z", "2024-01-01T02:00:00Z", "2h0m0s", "100"},
+			{"2", "7", "failed", "50000", "301", "invalid input", "", "", "", "2024-01-02T00:00:00Z", "2024-01-02T00:10:00Z", "10m0s", "0"},
+		}
+
+		if diff := cmp.Diff(rows, expected); diff != "" {
+			t.Errorf("%s: mismatch (-actual, +expected):\n%s", format, diff)
+		}
+	}
+
+	test(t, OutputFormatCSV, ',')
+	test(t, OutputFormatTSV, '\t')
+}
+
+func TestWriteWorkflowsWithTable(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflows(&buf, OutputFormatTable, newTestWorkflows()); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"ID  STATUS   CREATED              DURATION  DESCRIPTION\n" +
+		"1   success  2024-01-01 00:00:00  2h0m0s    sample, one\n" +
+		"2   failed   2024-01-02 00:00:00  10m0s     \n"
+
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	buf.Reset()
+
+	if err := WriteWorkflows(&buf, OutputFormatWide, newTestWorkflows()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "FAILURE CODE") || !strings.Contains(buf.String(), "invalid input") {
+		t.Errorf("expected all fields in the wide table, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteWorkflowWithDetail(t *testing.T) {
+	var buf bytes.Buffer
+
+	if err := WriteWorkflow(&buf, OutputFormatDetail, newTestWorkflows()[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"Workflow ID     : 1\n" +
+		"Status          : success (20000)\n" +
+		"Message         : \n" +
+		"Process         : snapgatk-20230626_1\n" +
+		"Description     : sample, one\n" +
+		"Created Date    : 2024-01-01 00:00:00 +0000 UTC\n" +
+		"End Date        : 2024-01-01 02:00:00 +0000 UTC\n" +
+		"Wall Clock Time : 2h0m0s\n" +
+		"Bases Processed : 100\n"
+
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
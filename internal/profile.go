@@ -0,0 +1,300 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	configDirName  = "msgenctl"
+	configFileName = "config.yaml"
+)
+
+// Profile is a named set of flag values, keyed by flag name (e.g.,
+// `base-url`).
+type Profile map[string]string
+
+// Config is the msgenctl configuration file.
+type Config struct {
+	CurrentProfile string             `yaml:"current-profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles,omitempty"`
+
+	// Regions adds or overrides entries in the built-in region table.
+	Regions Regions `yaml:"regions,omitempty"`
+
+	// FailureCodes explains failure codes in workflow output (see
+	// `ExplainFailure`).
+	FailureCodes FailureCodes `yaml:"failure-codes,omitempty"`
+
+	// Prices are the prices per gigabase of the `usage` report.
+	Prices Prices `yaml:"prices,omitempty"`
+}
+
+// DefaultConfigPath returns the path to the configuration file in the user
+// configuration directory, e.g., `~/.config/msgenctl/config.yaml`.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, configDirName, configFileName), nil
+}
+
+// LoadConfig reads the configuration file at the given path.
+//
+// A missing file is not an error and returns an empty configuration.
+func LoadConfig(path string) (Config, error) {
+	config := Config{}
+
+	data, err := os.ReadFile(path)
+
+	if errors.Is(err, fs.ErrNotExist) {
+		return config, nil
+	} else if err != nil {
+		return config, err
+	}
+
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("invalid config file: %s: %w", path, err)
+	}
+
+	return config, nil
+}
+
+// SaveConfig writes the configuration file to the given path, creating its
+// parent directory if needed.
+//
+// The file may contain secrets, so it is only readable by the current user.
+func SaveConfig(path string, config Config) error {
+	var buf bytes.Buffer
+
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&config); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, buf.Bytes(), 0o600)
+}
+
+// Profile returns the profile with the given name.
+//
+// If name is empty, the current profile is used. If there is no current
+// profile, an empty profile is returned.
+func (c *Config) Profile(name string) (Profile, error) {
+	if len(name) == 0 {
+		name = c.CurrentProfile
+	}
+
+	if len(name) == 0 {
+		return Profile{}, nil
+	}
+
+	profile, ok := c.Profiles[name]
+
+	if !ok {
+		return nil, fmt.Errorf("unknown profile: %q", name)
+	}
+
+	return profile, nil
+}
+
+// ProfileNames returns the sorted names of all profiles.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// ValidateProfile checks the values of the profile that can be checked
+// offline, i.e., the base URL, storage auth modes, and storage connection
+// strings. A connection string for a storage auth mode that uses a Microsoft
+// Entra ID credential needs no AccountKey or SharedAccessSignature.
+//
+// All problems are reported.
+func ValidateProfile(profile Profile) error {
+	errs := []error{}
+
+	if baseURL, ok := profile["base-url"]; ok {
+		if err := ValidateBaseURL(baseURL); err != nil {
+			errs = append(errs, fmt.Errorf("base-url: %w", err))
+		}
+	}
+
+	for _, prefix := range []string{"input", "output"} {
+		authKey := fmt.Sprintf("%s-storage-auth", prefix)
+		auth := StorageAuthMode("")
+
+		if rawAuth, ok := profile[authKey]; ok {
+			mode, err := ParseStorageAuthMode(rawAuth)
+
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", authKey, err))
+			}
+
+			auth = mode
+		}
+
+		key := fmt.Sprintf("%s-storage-connection-string", prefix)
+		rawConnectionString, ok := profile[key]
+
+		if !ok {
+			continue
+		}
+
+		connectionString, err := ParseConnectionString(rawConnectionString)
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			continue
+		}
+
+		if len(connectionString.AccountName) == 0 {
+			errs = append(errs, fmt.Errorf("%s: missing AccountName", key))
+		}
+
+		if !auth.UsesTokenCredential() && len(connectionString.AccountKey) == 0 && len(connectionString.SharedAccessSignature) == 0 {
+			errs = append(errs, fmt.Errorf("%s: missing AccountKey or SharedAccessSignature", key))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// MaskSecret returns the secret with its sensitive parts replaced.
+//
+// Connection strings keep their nonsecret fields, e.g., the account name.
+// Any other value is masked entirely.
+func MaskSecret(value string) string {
+	connectionString, err := ParseConnectionString(value)
+
+	if err != nil || len(connectionString.AccountName) == 0 {
+		return secretMask
+	}
+
+	fields := strings.Split(strings.TrimRight(value, ";"), ";")
+
+	for i, field := range fields {
+		key, _, _ := strings.Cut(field, "=")
+
+		if key == "AccountKey" || key == "SharedAccessSignature" {
+			fields[i] = key + "=" + secretMask
+		}
+	}
+
+	return strings.Join(fields, ";")
+}
+
+// ApplyProfile sets each flag that was not explicitly given on the command
+// line to its value in the profile, if present.
+//
+// Profile keys that do not match a flag are ignored.
+func ApplyProfile(flags *pflag.FlagSet, profile Profile) error {
+	return applyFlagSource(flags, func(name string) (string, string, bool) {
+		value, ok := profile[name]
+		return fmt.Sprintf("profile value for %s", name), value, ok
+	})
+}
+
+// FlagSources records which source set each flag, in order of precedence,
+// e.g., the command line, then environment variables, and then a profile.
+type FlagSources struct {
+	sources [][]string
+}
+
+// Record adds a source with the flags set since the previous source was
+// recorded. The first source is the command line.
+func (s *FlagSources) Record(flags *pflag.FlagSet) {
+	names := []string{}
+
+	flags.Visit(func(flag *pflag.Flag) {
+		if s.Rank(flag.Name) == len(s.sources) {
+			names = append(names, flag.Name)
+		}
+	})
+
+	s.sources = append(s.sources, names)
+}
+
+// Rank returns the index of the source that set the flag, where a lower
+// index takes precedence, or the number of sources if none did.
+func (s *FlagSources) Rank(name string) int {
+	for i, names := range s.sources {
+		if slices.Contains(names, name) {
+			return i
+		}
+	}
+
+	return len(s.sources)
+}
+
+// CommandLineOnlyAnnotation marks a flag that is only read from the command
+// line, not from environment variables or profiles, e.g., the `status`
+// filters, as `--description` would otherwise share a profile value with
+// `submit`.
+const CommandLineOnlyAnnotation = "msgenctl_command_line_only"
+
+// applyFlagSource sets each flag that was not explicitly given on the command
+// line to the value returned by lookup, if any.
+//
+// lookup returns a description of where the value came from, used in errors,
+// the value, and whether the value is present.
+//
+// A flag is skipped if another source of the same secret is already set (see
+// `SecretSourceAnnotation`) or if it is command line only (see
+// `CommandLineOnlyAnnotation`).
+func applyFlagSource(
+	flags *pflag.FlagSet,
+	lookup func(name string) (string, string, bool),
+) error {
+	unset := []*pflag.Flag{}
+
+	flags.VisitAll(func(flag *pflag.Flag) {
+		_, isCommandLineOnly := flag.Annotations[CommandLineOnlyAnnotation]
+
+		if !flag.Changed && !isCommandLineOnly {
+			unset = append(unset, flag)
+		}
+	})
+
+	for _, flag := range unset {
+		if isSecretSourceSet(flags, flag) {
+			continue
+		}
+
+		description, value, ok := lookup(flag.Name)
+
+		if !ok {
+			continue
+		}
+
+		if err := flags.Set(flag.Name, value); err != nil {
+			return fmt.Errorf("invalid %s: %w", description, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	data := []byte(`current-profile: prod-eastus
+profiles:
+  prod-eastus:
+    base-url: https://eastus.api.microsoftgenomics.net
+    access-key: secret
+    output-overwrite: true
+  dev-westus:
+    base-url: https://westus2.api.microsoftgenomics.net
+`)
+
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := LoadConfig(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Config{
+		CurrentProfile: "prod-eastus",
+		Profiles: map[string]Profile{
+			"prod-eastus": {
+				"base-url":         "https://eastus.api.microsoftgenomics.net",
+				"access-key":       "secret",
+				"output-overwrite": "true",
+			},
+			"dev-westus": {
+				"base-url": "https://westus2.api.microsoftgenomics.net",
+			},
+		},
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("config mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestLoadConfigWithMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+
+	actual, err := LoadConfig(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, Config{}); len(diff) != 0 {
+		t.Errorf("config mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "msgenctl", "config.yaml")
+
+	expected := Config{
+		CurrentProfile: "prod",
+		Profiles: map[string]Profile{
+			"prod": {"base-url": "https://prod.example.com"},
+		},
+	}
+
+	if err := SaveConfig(path, expected); err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := os.Stat(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if runtime.GOOS != "windows" && info.Mode().Perm() != 0o600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	actual, err := LoadConfig(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("config mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestConfigProfile(t *testing.T) {
+	config := Config{
+		CurrentProfile: "prod",
+		Profiles: map[string]Profile{
+			"prod": {"base-url": "https://prod.example.com"},
+			"dev":  {"base-url": "https://dev.example.com"},
+		},
+	}
+
+	test := func(t testing.TB, name string, expected string) {
+		t.Helper()
+
+		profile, err := config.Profile(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := profile["base-url"]; actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "", "https://prod.example.com")
+	test(t, "prod", "https://prod.example.com")
+	test(t, "dev", "https://dev.example.com")
+
+	if _, err := config.Profile("staging"); err == nil {
+		t.Error(`expected failure: name = "staging"`)
+	}
+
+	empty := Config{}
+	profile, err := empty.Profile("")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(profile) != 0 {
+		t.Errorf("expected empty profile, got %v", profile)
+	}
+}
+
+func TestValidateProfile(t *testing.T) {
+	valid := Profile{
+		"base-url":                         "https://example.com",
+		"input-storage-connection-string":  "AccountName=input;AccountKey=secret;",
+		"output-storage-connection-string": "AccountName=output;AccountKey=secret;",
+	}
+
+	if err := ValidateProfile(valid); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	invalid := Profile{
+		"base-url":                         "example.com",
+		"input-storage-connection-string":  "AccountName=input",
+		"output-storage-connection-string": "AccountName",
+	}
+
+	err := ValidateProfile(invalid)
+
+	if err == nil {
+		t.Fatal("expected failure")
+	}
+
+	for _, key := range []string{"base-url", "input-storage-connection-string", "output-storage-connection-string"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("expected error for %s, got %v", key, err)
+		}
+	}
+}
+
+func TestValidateProfileWithTokenCredential(t *testing.T) {
+	profile := Profile{
+		"input-storage-connection-string":  "AccountName=input",
+		"input-storage-auth":               StorageAuthModeManagedIdentity,
+		"output-storage-connection-string": "AccountName=output",
+		"output-storage-auth":              StorageAuthModeAzureCLI,
+	}
+
+	if err := ValidateProfile(profile); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+
+	profile["output-storage-auth"] = StorageAuthModeSharedKey
+
+	err := ValidateProfile(profile)
+
+	if err == nil || !strings.Contains(err.Error(), "output-storage-connection-string: missing AccountKey") {
+		t.Errorf("expected output-storage-connection-string failure, got %v", err)
+	}
+
+	profile["output-storage-auth"] = "password"
+
+	err = ValidateProfile(profile)
+
+	if err == nil || !strings.Contains(err.Error(), "output-storage-auth") {
+		t.Errorf("expected output-storage-auth failure, got %v", err)
+	}
+}
+
+func TestMaskSecret(t *testing.T) {
+	test := func(t testing.TB, value string, expected string) {
+		t.Helper()
+
+		actual := MaskSecret(value)
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "secret", "********")
+	test(t, "AccountName=msgenctl;AccountKey=secret;", "AccountName=msgenctl;AccountKey=********")
+	test(
+		t,
+		"AccountName=msgenctl;SharedAccessSignature=sv=2021&sig=secret",
+		"AccountName=msgenctl;SharedAccessSignature=********",
+	)
+}
+
+func TestApplyProfile(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	baseURL := flags.String("base-url", "", "")
+	accessKey := flags.String("access-key", "", "")
+	overwrite := flags.Bool("output-overwrite", false, "")
+
+	args := []string{
+		"--access-key", "flag-secret",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	profile := Profile{
+		"base-url":         "https://example.com",
+		"access-key":       "profile-secret",
+		"output-overwrite": "true",
+		"unknown":          "ignored",
+	}
+
+	if err := ApplyProfile(flags, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	if *baseURL != "https://example.com" {
+		t.Errorf("expected base-url from profile, got %q", *baseURL)
+	}
+
+	if *accessKey != "flag-secret" {
+		t.Errorf("expected access-key from flag, got %q", *accessKey)
+	}
+
+	if !*overwrite {
+		t.Error("expected output-overwrite from profile")
+	}
+}
+
+func TestApplyProfileWithInvalidValue(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.Bool("output-overwrite", false, "")
+
+	if err := ApplyProfile(flags, Profile{"output-overwrite": "maybe"}); err == nil {
+		t.Error(`expected failure: output-overwrite = "maybe"`)
+	}
+}
+
+func TestApplyProfileWithCommandLineOnlyFlag(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	description := flags.String("description", "", "")
+	flags.SetAnnotation("description", CommandLineOnlyAnnotation, []string{"true"})
+
+	if err := ApplyProfile(flags, Profile{"description": "sample"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if *description != "" {
+		t.Errorf("expected description to not be set from profile, got %q", *description)
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+const secretMask = "********"
+
+// minRegisteredSecretLength avoids masking short, common substrings when a
+// secret is, e.g., a single character.
+const minRegisteredSecretLength = 4
+
+var secretPatterns = []*regexp.Regexp{
+	// SAS signature, e.g., in a URL query or SAS token.
+	regexp.MustCompile(`(?i)(sig=)[^&\s"';]+`),
+	// Connection string keys.
+	regexp.MustCompile(`(?i)(\bAccountKey=)[^;\s"']+`),
+	regexp.MustCompile(`(?i)(\bSharedAccessSignature=)[^;\s"']+`),
+	// API access key header, e.g., in a request dump.
+	regexp.MustCompile(`(?i)(\bOcp-Apim-Subscription-Key"?\s*[:=]\s*\[?"?)[^\s"\],]+`),
+}
+
+var registeredSecrets = struct {
+	sync.RWMutex
+	values []string
+}{}
+
+// RegisterSecret adds a secret value, e.g., an access key, to be masked by
+// `Redact` wherever it appears.
+func RegisterSecret(value string) {
+	if len(value) < minRegisteredSecretLength {
+		return
+	}
+
+	registeredSecrets.Lock()
+	defer registeredSecrets.Unlock()
+
+	if !slices.Contains(registeredSecrets.values, value) {
+		registeredSecrets.values = append(registeredSecrets.values, value)
+	}
+}
+
+// Redact masks secrets in s: SAS signatures (`sig=`), connection string
+// account keys and SAS, `Ocp-Apim-Subscription-Key` header values, and any
+// registered secret (see `RegisterSecret`).
+func Redact(s string) string {
+	registeredSecrets.RLock()
+
+	for _, secret := range registeredSecrets.values {
+		s = strings.ReplaceAll(s, secret, secretMask)
+	}
+
+	registeredSecrets.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+secretMask)
+	}
+
+	return s
+}
+
+// RedactingHandler is a `slog.Handler` that masks secrets (see `Redact`) in
+// the message and attribute values before passing records to another
+// handler.
+//
+// Values that are not strings, numbers, booleans, times, or durations are
+// formatted as strings before being redacted. `slog.LogValuer`
+// implementations are resolved first, so they can still log structured,
+// already masked values.
+type RedactingHandler struct {
+	handler slog.Handler
+}
+
+// NewRedactingHandler returns a handler that redacts records before passing
+// them to the given handler.
+func NewRedactingHandler(handler slog.Handler) *RedactingHandler {
+	return &RedactingHandler{handler: handler}
+}
+
+func (h *RedactingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+func (h *RedactingHandler) Handle(ctx context.Context, record slog.Record) error {
+	redacted := slog.NewRecord(record.Time, record.Level, Redact(record.Message), record.PC)
+
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(redactAttr(attr))
+		return true
+	})
+
+	return h.handler.Handle(ctx, redacted)
+}
+
+func (h *RedactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+
+	for i, attr := range attrs {
+		redacted[i] = redactAttr(attr)
+	}
+
+	return &RedactingHandler{handler: h.handler.WithAttrs(redacted)}
+}
+
+func (h *RedactingHandler) WithGroup(name string) slog.Handler {
+	return &RedactingHandler{handler: h.handler.WithGroup(name)}
+}
+
+func redactAttr(attr slog.Attr) slog.Attr {
+	return slog.Attr{Key: attr.Key, Value: redactValue(attr.Value)}
+}
+
+func redactValue(value slog.Value) slog.Value {
+	value = value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.StringValue(Redact(value.String()))
+	case slog.KindGroup:
+		attrs := value.Group()
+		redacted := make([]slog.Attr, len(attrs))
+
+		for i, attr := range attrs {
+			redacted[i] = redactAttr(attr)
+		}
+
+		return slog.GroupValue(redacted...)
+	case slog.KindAny:
+		return slog.StringValue(Redact(fmt.Sprintf("%+v", value.Any())))
+	default:
+		return value
+	}
+}
+
+func maskNonEmpty(s string) string {
+	if len(s) == 0 {
+		return ""
+	}
+
+	return secretMask
+}
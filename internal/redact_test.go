@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const (
+	testAccessKey    = "0123456789abcdef0123456789abcdef"
+	testAccountKey   = "bXNnZW5jdGwtYWNjb3VudC1rZXk="
+	testSASSignature = "c2lnbmF0dXJl"
+)
+
+// newTestLogger returns a logger that redacts records and writes them to
+// the buffer in the given format.
+func newTestLogger(buf *bytes.Buffer, json bool) *slog.Logger {
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+
+	var handler slog.Handler = slog.NewTextHandler(buf, options)
+
+	if json {
+		handler = slog.NewJSONHandler(buf, options)
+	}
+
+	return slog.New(NewRedactingHandler(handler))
+}
+
+func assertNoSecrets(t testing.TB, output string) {
+	t.Helper()
+
+	for _, secret := range []string{testAccessKey, testAccountKey, testSASSignature} {
+		if strings.Contains(output, secret) {
+			t.Errorf("secret %q found in log output:\n%s", secret, output)
+		}
+	}
+}
+
+func TestRedact(t *testing.T) {
+	RegisterSecret(testAccessKey)
+
+	test := func(t testing.TB, s string, expected string) {
+		t.Helper()
+
+		if actual := Redact(s); actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(
+		t,
+		"sample.bam?sv=2021-06-08&sig="+testSASSignature+"&sp=r",
+		"sample.bam?sv=2021-06-08&sig=********&sp=r",
+	)
+	test(
+		t,
+		"AccountName=msgenctl;AccountKey="+testAccountKey+";EndpointSuffix=core.windows.net",
+		"AccountName=msgenctl;AccountKey=********;EndpointSuffix=core.windows.net",
+	)
+	test(
+		t,
+		"AccountName=msgenctl;SharedAccessSignature=sv=2021-06-08&sig="+testSASSignature,
+		"AccountName=msgenctl;SharedAccessSignature=********",
+	)
+	test(t, "Ocp-Apim-Subscription-Key: opaque", "Ocp-Apim-Subscription-Key: ********")
+	test(t, "map[Ocp-Apim-Subscription-Key:[opaque]]", "map[Ocp-Apim-Subscription-Key:[********]]")
+	test(t, `{"Ocp-Apim-Subscription-Key":["opaque"]}`, `{"Ocp-Apim-Subscription-Key":["********"]}`)
+	test(t, "key="+testAccessKey, "key=********")
+	test(t, "nothing to see here", "nothing to see here")
+}
+
+func TestRedactingHandler(t *testing.T) {
+	RegisterSecret(testAccessKey)
+
+	storage := StorageConfig{
+		AccountName:           "msgenctl",
+		AccountKey:            testAccountKey,
+		SharedAccessSignature: "sv=2021-06-08&sig=" + testSASSignature,
+		ContainerName:         "data",
+	}
+
+	connectionString, err := ParseConnectionString("AccountName=msgenctl;AccountKey=" + testAccountKey)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newWorkflow := NewWorkflow{
+		Process: "snapgatk-20190409_1",
+		InputArgs: NewWorkflowInputArgs{
+			AccountName:      "msgenctl",
+			BlobNamesWithSAS: "sample.bam?sv=2021-06-08&sig=" + testSASSignature,
+		},
+		OutputArgs: NewWorkflowOutputArgs{
+			AccountName:  "msgenctl",
+			ContainerSAS: "sv=2021-06-08&sig=" + testSASSignature,
+		},
+	}
+
+	header := http.Header{}
+	addHeaders(&header, testAccessKey)
+
+	for _, json := range []bool{false, true} {
+		var buf bytes.Buffer
+		logger := newTestLogger(&buf, json)
+
+		logger.Debug("payload", "data", newWorkflow)
+		logger.Info("storage", "config", storage, "connectionString", connectionString)
+		logger.Info("headers", "header", header)
+		logger.Error("failed", "error", errors.New("GET ?sig="+testSASSignature))
+		logger.With("accessKey", testAccessKey).WithGroup("group").Info("with", "key", testAccessKey)
+		logger.Info("message with AccountKey=" + testAccountKey)
+
+		output := buf.String()
+
+		assertNoSecrets(t, output)
+
+		if !strings.Contains(output, "msgenctl") {
+			t.Errorf("expected nonsecret values in log output:\n%s", output)
+		}
+	}
+}
+
+func TestClientLogsWithoutSecrets(t *testing.T) {
+	var buf bytes.Buffer
+
+	defaultLogger := slog.Default()
+	slog.SetDefault(newTestLogger(&buf, false))
+	defer slog.SetDefault(defaultLogger)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		// Echo the request, including the access key header and payload, as
+		// some error responses do.
+		rw.WriteHeader(http.StatusBadRequest)
+		r.Header.Write(rw)
+
+		var body bytes.Buffer
+		body.ReadFrom(r.Body)
+		rw.Write(body.Bytes())
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, testAccessKey)
+
+	newWorkflow := NewWorkflow{
+		InputArgs: NewWorkflowInputArgs{
+			BlobNamesWithSAS: "sample.bam?sv=2021-06-08&sig=" + testSASSignature,
+		},
+	}
+
+	_, err := client.Post("/api/workflows", newWorkflow)
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	slog.Error("submit", "error", err)
+
+	assertNoSecrets(t, buf.String())
+	assertNoSecrets(t, err.Error())
+}
@@ -0,0 +1,210 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+)
+
+const regionBaseURLFormat = "https://%s.api.microsoftgenomics.net"
+
+// defaultRegionNames are the Azure regions where Microsoft Genomics is
+// available.
+var defaultRegionNames = []string{
+	"australiaeast",
+	"canadacentral",
+	"centralindia",
+	"eastus",
+	"japaneast",
+	"koreacentral",
+	"northcentralus",
+	"northeurope",
+	"southcentralus",
+	"southeastasia",
+	"switzerlandnorth",
+	"uksouth",
+	"westeurope",
+	"westus2",
+}
+
+// Regions maps Azure region names (e.g., `eastus`) to Microsoft Genomics API
+// base URLs.
+type Regions map[string]string
+
+// DefaultRegions returns the built-in table of supported regions.
+func DefaultRegions() Regions {
+	regions := Regions{}
+
+	for _, name := range defaultRegionNames {
+		regions[name] = fmt.Sprintf(regionBaseURLFormat, name)
+	}
+
+	return regions
+}
+
+// Merge returns a copy of the regions with the given overrides added or
+// replaced.
+func (r Regions) Merge(overrides Regions) Regions {
+	regions := Regions{}
+
+	for name, baseURL := range r {
+		regions[name] = baseURL
+	}
+
+	for name, baseURL := range overrides {
+		regions[normalizeRegionName(name)] = baseURL
+	}
+
+	return regions
+}
+
+// Names returns the sorted region names.
+func (r Regions) Names() []string {
+	names := make([]string, 0, len(r))
+
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// BaseURL returns the base URL of the region.
+func (r Regions) BaseURL(region string) (string, error) {
+	baseURL, ok := r[normalizeRegionName(region)]
+
+	if !ok {
+		return "", fmt.Errorf("unsupported region: %q", region)
+	}
+
+	return baseURL, nil
+}
+
+// RegionForBaseURL returns the region whose base URL has the same host as the
+// given base URL.
+func (r Regions) RegionForBaseURL(baseURL string) (string, bool) {
+	host := baseURLHost(baseURL)
+
+	if len(host) == 0 {
+		return "", false
+	}
+
+	for _, name := range r.Names() {
+		if baseURLHost(r[name]) == host {
+			return name, true
+		}
+	}
+
+	return "", false
+}
+
+// ApplyRegion resolves the `base-url` and `region` flags against each other.
+//
+// If only one of them is set, the other is derived from it: the base URL of
+// the region, or the region of the base URL, if known. If both are set by
+// different sources, the one of higher precedence (see `FlagSources`) wins,
+// e.g., `--region` on the command line replaces a base URL from a profile.
+// If both are set by the same source, a base URL of another known region is
+// an error.
+func ApplyRegion(flags *pflag.FlagSet, regions Regions, sources *FlagSources) error {
+	baseURL, err := flags.GetString("base-url")
+
+	if err != nil {
+		return err
+	}
+
+	region, err := flags.GetString("region")
+
+	if err != nil {
+		return err
+	}
+
+	regionRank := sources.Rank("region")
+	baseURLRank := sources.Rank("base-url")
+
+	if len(region) > 0 && (len(baseURL) == 0 || regionRank < baseURLRank) {
+		regionBaseURL, err := regions.BaseURL(region)
+
+		if err != nil {
+			return err
+		}
+
+		return flags.Set("base-url", regionBaseURL)
+	}
+
+	if len(baseURL) == 0 {
+		return nil
+	}
+
+	baseURLRegion, ok := regions.RegionForBaseURL(baseURL)
+
+	if !ok || baseURLRegion == normalizeRegionName(region) {
+		return nil
+	}
+
+	if len(region) == 0 || baseURLRank < regionRank {
+		return flags.Set("region", baseURLRegion)
+	}
+
+	return fmt.Errorf("base URL %q is in region %q, not %q: set either base-url or region", baseURL, baseURLRegion, region)
+}
+
+// RegionMismatches returns a warning for each storage account whose declared
+// region differs from the service region.
+//
+// Storage accounts without a declared region, or a service without a known
+// region, are not checked.
+func RegionMismatches(config SubmitConfig) []string {
+	serviceRegion := normalizeRegionName(config.Service.Region)
+
+	if len(serviceRegion) == 0 {
+		return nil
+	}
+
+	warnings := []string{}
+
+	storages := []struct {
+		name   string
+		config StorageConfig
+	}{
+		{"input", config.Input.Storage},
+		{"output", config.Output.Storage},
+	}
+
+	for _, storage := range storages {
+		storageRegion := normalizeRegionName(storage.config.Region)
+
+		if len(storageRegion) > 0 && storageRegion != serviceRegion {
+			warnings = append(warnings, fmt.Sprintf(
+				"%s storage account %q is in %s, but the service is in %s",
+				storage.name,
+				storage.config.AccountName,
+				storageRegion,
+				serviceRegion,
+			))
+		}
+	}
+
+	return warnings
+}
+
+// normalizeRegionName converts display names (e.g., `East US`) to region
+// names (e.g., `eastus`).
+func normalizeRegionName(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, " ", ""))
+}
+
+func baseURLHost(baseURL string) string {
+	u, err := url.Parse(baseURL)
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.ToLower(u.Host)
+}
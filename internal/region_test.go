@@ -0,0 +1,205 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func TestRegionsBaseURL(t *testing.T) {
+	regions := DefaultRegions().Merge(Regions{
+		"contoso": "https://genomics.contoso.example",
+		"East US": "https://eastus.example",
+	})
+
+	test := func(t testing.TB, region string, expected string) {
+		actual, err := regions.BaseURL(region)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "westus2", "https://westus2.api.microsoftgenomics.net")
+	test(t, "West US 2", "https://westus2.api.microsoftgenomics.net")
+	test(t, "contoso", "https://genomics.contoso.example")
+	test(t, "eastus", "https://eastus.example")
+
+	if _, err := regions.BaseURL("moon"); err == nil {
+		t.Error("expected error for unsupported region")
+	}
+}
+
+func TestRegionsRegionForBaseURL(t *testing.T) {
+	regions := DefaultRegions()
+
+	test := func(t testing.TB, baseURL string, expected string, expectedOk bool) {
+		actual, ok := regions.RegionForBaseURL(baseURL)
+
+		if ok != expectedOk {
+			t.Fatalf("expected ok = %v, got %v", expectedOk, ok)
+		}
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "https://eastus.api.microsoftgenomics.net", "eastus", true)
+	test(t, "https://EastUS.api.microsoftgenomics.net/", "eastus", true)
+	test(t, "https://genomics.contoso.example", "", false)
+	test(t, "", "", false)
+}
+
+func TestApplyRegion(t *testing.T) {
+	regions := DefaultRegions()
+
+	// test applies the command line args, and then the profile values to
+	// flags not given on the command line.
+	test := func(t testing.TB, args []string, profile Profile, expectedBaseURL string, expectedRegion string) {
+		t.Helper()
+
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		baseURL := flags.String("base-url", "", "")
+		region := flags.String("region", "", "")
+
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		sources := FlagSources{}
+		sources.Record(flags)
+
+		if err := ApplyProfile(flags, profile); err != nil {
+			t.Fatal(err)
+		}
+
+		sources.Record(flags)
+
+		if err := ApplyRegion(flags, regions, &sources); err != nil {
+			t.Fatal(err)
+		}
+
+		if *baseURL != expectedBaseURL {
+			t.Errorf("expected base URL %q, got %q", expectedBaseURL, *baseURL)
+		}
+
+		if *region != expectedRegion {
+			t.Errorf("expected region %q, got %q", expectedRegion, *region)
+		}
+	}
+
+	test(t, []string{"--region", "eastus"}, nil, "https://eastus.api.microsoftgenomics.net", "eastus")
+	test(
+		t,
+		[]string{"--base-url", "https://westus2.api.microsoftgenomics.net"},
+		nil,
+		"https://westus2.api.microsoftgenomics.net",
+		"westus2",
+	)
+	test(
+		t,
+		[]string{"--region", "eastus", "--base-url", "https://example.com"},
+		nil,
+		"https://example.com",
+		"eastus",
+	)
+	test(t, []string{"--base-url", "https://example.com"}, nil, "https://example.com", "")
+	test(t, []string{}, nil, "", "")
+	test(
+		t,
+		[]string{"--region", "eastus"},
+		Profile{"base-url": "https://westus2.api.microsoftgenomics.net"},
+		"https://eastus.api.microsoftgenomics.net",
+		"eastus",
+	)
+	test(
+		t,
+		[]string{"--region", "eastus"},
+		Profile{"base-url": "https://example.com"},
+		"https://eastus.api.microsoftgenomics.net",
+		"eastus",
+	)
+	test(
+		t,
+		[]string{"--base-url", "https://westus2.api.microsoftgenomics.net"},
+		Profile{"region": "eastus"},
+		"https://westus2.api.microsoftgenomics.net",
+		"westus2",
+	)
+	test(
+		t,
+		[]string{},
+		Profile{"region": "eastus"},
+		"https://eastus.api.microsoftgenomics.net",
+		"eastus",
+	)
+}
+
+func TestApplyRegionWithUnsupportedRegion(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.String("base-url", "", "")
+	flags.String("region", "", "")
+
+	if err := flags.Parse([]string{"--region", "moon"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := FlagSources{}
+	sources.Record(flags)
+
+	if err := ApplyRegion(flags, DefaultRegions(), &sources); err == nil {
+		t.Error("expected error for unsupported region")
+	}
+}
+
+func TestApplyRegionWithConflictingRegion(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.String("base-url", "", "")
+	flags.String("region", "", "")
+
+	args := []string{"--region", "eastus", "--base-url", "https://westus2.api.microsoftgenomics.net"}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	sources := FlagSources{}
+	sources.Record(flags)
+
+	if err := ApplyRegion(flags, DefaultRegions(), &sources); err == nil {
+		t.Error("expected error for a base URL in another region")
+	}
+}
+
+func TestRegionMismatches(t *testing.T) {
+	config := SubmitConfig{
+		Service: ServiceConfig{Region: "eastus"},
+		Input: InputConfig{
+			Storage: StorageConfig{AccountName: "input", Region: "East US"},
+		},
+		Output: OutputConfig{
+			Storage: StorageConfig{AccountName: "output", Region: "westus2"},
+		},
+	}
+
+	actual := RegionMismatches(config)
+	expected := []string{
+		`output storage account "output" is in westus2, but the service is in eastus`,
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	config.Service.Region = ""
+
+	if actual := RegionMismatches(config); len(actual) != 0 {
+		t.Errorf("expected no mismatches without a service region, got %v", actual)
+	}
+}
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"golang.org/x/time/rate"
+)
+
+type withoutRetriesKey struct{}
+
+// withoutRetries marks requests with the context as not retryable.
+func withoutRetries(ctx context.Context) context.Context {
+	return context.WithValue(ctx, withoutRetriesKey{}, true)
+}
+
+// newRetryPolicy returns a retryablehttp retry policy that retries transport
+// errors and responses with one of the given status codes.
+//
+// Requests marked with `withoutRetries` and requests without a recorded
+// response when replaying (see `ReplayTransport`) are never retried.
+func newRetryPolicy(statusCodes []int) retryablehttp.CheckRetry {
+	return func(ctx context.Context, response *http.Response, err error) (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+
+		if ctx.Value(withoutRetriesKey{}) != nil {
+			return false, nil
+		}
+
+		if errors.Is(err, errNotRecorded) {
+			return false, nil
+		}
+
+		if err != nil {
+			// Transport errors, except those that will not succeed on retry,
+			// e.g., invalid certificates.
+			return retryablehttp.DefaultRetryPolicy(ctx, nil, err)
+		}
+
+		return slices.Contains(statusCodes, response.StatusCode), nil
+	}
+}
+
+// rateLimitedTransport waits for a token from a shared token bucket before
+// sending each request.
+type rateLimitedTransport struct {
+	base    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+// newRateLimitedTransport returns a transport that sends at most `limit`
+// requests per second, with bursts of at most `burst` requests.
+func newRateLimitedTransport(base http.RoundTripper, limit float64, burst int) *rateLimitedTransport {
+	return &rateLimitedTransport{
+		base:    base,
+		limiter: rate.NewLimiter(rate.Limit(limit), max(burst, 1)),
+	}
+}
+
+func (t *rateLimitedTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(request.Context()); err != nil {
+		return nil, err
+	}
+
+	return t.base.RoundTrip(request)
+}
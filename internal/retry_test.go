@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetriesStatusCodes(t *testing.T) {
+	test := func(t testing.TB, statusCode int, statusCodes []int, expectedRequests int32) {
+		var requests atomic.Int32
+
+		server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			rw.WriteHeader(statusCode)
+		}))
+
+		defer server.Close()
+
+		options := DefaultClientOptions()
+		options.RetryMax = 2
+		options.RetryWaitMin = time.Millisecond
+		options.RetryWaitMax = time.Millisecond
+		options.RetryableStatusCodes = statusCodes
+
+		client := NewClientWithOptions(server.URL, "secret", options)
+
+		if _, err := client.Get("/api/workflows"); err == nil {
+			t.Fatal("expected error")
+		}
+
+		if n := requests.Load(); n != expectedRequests {
+			t.Errorf("expected %d requests, got %d", expectedRequests, n)
+		}
+	}
+
+	test(t, http.StatusServiceUnavailable, []int{http.StatusServiceUnavailable}, 3)
+	test(t, http.StatusInternalServerError, []int{http.StatusServiceUnavailable}, 1)
+	test(t, http.StatusNotFound, []int{http.StatusNotFound}, 3)
+	test(t, http.StatusServiceUnavailable, nil, 1)
+}
+
+func TestClientDoesNotRetryPost(t *testing.T) {
+	var requests atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+
+	client := NewClientWithOptions(server.URL, "secret", options)
+
+	if _, err := client.Post("/api/workflows", NewWorkflow{}); err == nil {
+		t.Fatal("expected error")
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 request, got %d", n)
+	}
+}
+
+func TestClientBackoffHonorsRetryAfter(t *testing.T) {
+	client := NewClient("https://example.com", "secret")
+
+	response := &http.Response{
+		StatusCode: http.StatusTooManyRequests,
+		Header:     http.Header{"Retry-After": []string{"7"}},
+	}
+
+	actual := client.httpClient.Backoff(time.Millisecond, time.Second, 0, response)
+	expected := 7 * time.Second
+
+	if actual != expected {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	test := func(t testing.TB, s string, expected time.Duration) {
+		if actual := parseRetryAfter(s, now); actual != expected {
+			t.Errorf("%q: expected %v, got %v", s, expected, actual)
+		}
+	}
+
+	test(t, "", 0)
+	test(t, "120", 2*time.Minute)
+	test(t, "-1", 0)
+	test(t, "Mon, 01 Jan 2024 00:00:30 GMT", 30*time.Second)
+	test(t, "Sun, 31 Dec 2023 00:00:00 GMT", 0)
+	test(t, "soon", 0)
+}
+
+func TestClientRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.Write([]byte("[]"))
+	}))
+
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.RateLimit = 20
+	options.RateLimitBurst = 1
+
+	client := NewClientWithOptions(server.URL, "secret", options)
+
+	start := time.Now()
+
+	for i := 0; i < 5; i++ {
+		response, err := client.Get("/api/workflows")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		response.Body.Close()
+	}
+
+	// The first request uses the burst; each of the other 4 waits 50ms.
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected requests to be rate limited, took %v", elapsed)
+	}
+}
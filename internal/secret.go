@@ -0,0 +1,163 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// SecretSourceAnnotation is the flag annotation that groups the flags that are
+// alternative sources of the same secret, i.e., `<name>`, `<name>-file`, and
+// `<name>-command`. Its value is the name of the secret flag.
+const SecretSourceAnnotation = "msgenctl_secret_source"
+
+const (
+	secretFileSuffix    = "-file"
+	secretCommandSuffix = "-command"
+
+	stdinPath = "-"
+)
+
+// SecretFlagNames returns the names of the flags that are sources of the
+// secret with the given flag name.
+func SecretFlagNames(name string) []string {
+	return []string{name, name + secretFileSuffix, name + secretCommandSuffix}
+}
+
+// ReadSecret returns the secret for the given flag name.
+//
+// The secret is read from the first nonempty source of `<name>` (the value
+// itself), `<name>-file` (a path to a file or `-` for stdin), or
+// `<name>-command` (a command whose stdout is the secret). Trailing newlines
+// are removed from files and command output. Missing `-file` or `-command`
+// flags are treated as empty.
+//
+// The secret is registered to be redacted from logs (see `RegisterSecret`).
+func ReadSecret(flags *pflag.FlagSet, name string) (string, error) {
+	secret, err := readSecret(flags, name)
+
+	if err != nil {
+		return "", err
+	}
+
+	RegisterSecret(secret)
+
+	return secret, nil
+}
+
+func readSecret(flags *pflag.FlagSet, name string) (string, error) {
+	value, err := flags.GetString(name)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(value) > 0 {
+		return value, nil
+	}
+
+	if path := lookupString(flags, name+secretFileSuffix); len(path) > 0 {
+		data, err := readSecretFile(path)
+
+		if err != nil {
+			return "", fmt.Errorf("could not read %s%s: %w", name, secretFileSuffix, err)
+		}
+
+		return trimNewlines(data), nil
+	}
+
+	if command := lookupString(flags, name+secretCommandSuffix); len(command) > 0 {
+		data, err := runSecretCommand(command)
+
+		if err != nil {
+			return "", fmt.Errorf("could not run %s%s: %w", name, secretCommandSuffix, err)
+		}
+
+		return trimNewlines(data), nil
+	}
+
+	return "", nil
+}
+
+// isSecretSourceSet returns whether the flag is a secret source and another
+// source of the same secret was already set.
+func isSecretSourceSet(flags *pflag.FlagSet, flag *pflag.Flag) bool {
+	group, ok := flag.Annotations[SecretSourceAnnotation]
+
+	if !ok {
+		return false
+	}
+
+	set := false
+
+	flags.VisitAll(func(other *pflag.Flag) {
+		if other.Changed && other.Name != flag.Name {
+			otherGroup, ok := other.Annotations[SecretSourceAnnotation]
+			set = set || (ok && otherGroup[0] == group[0])
+		}
+	})
+
+	return set
+}
+
+func lookupString(flags *pflag.FlagSet, name string) string {
+	if flags.Lookup(name) == nil {
+		return ""
+	}
+
+	value, _ := flags.GetString(name)
+
+	return value
+}
+
+var readStdin = sync.OnceValues(func() ([]byte, error) {
+	return io.ReadAll(os.Stdin)
+})
+
+// readSecretFile reads the file at the given path, or stdin if the path is
+// `-`.
+//
+// stdin is only read once, so multiple secrets given as `-` share the same
+// value, e.g., when the input and output storage accounts are the same.
+func readSecretFile(path string) ([]byte, error) {
+	if path == stdinPath {
+		return readStdin()
+	}
+
+	return os.ReadFile(path)
+}
+
+// runSecretCommand runs the command using the system shell and returns its
+// stdout. stderr is passed through, e.g., for credential helper prompts.
+func runSecretCommand(command string) ([]byte, error) {
+	var cmd *exec.Cmd
+
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+
+	var stdout bytes.Buffer
+
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, err
+	}
+
+	return stdout.Bytes(), nil
+}
+
+func trimNewlines(data []byte) string {
+	return strings.TrimRight(string(data), "\r\n")
+}
@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func newSecretFlagSet(t testing.TB, args []string) *pflag.FlagSet {
+	t.Helper()
+
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+
+	for _, name := range SecretFlagNames("access-key") {
+		flags.String(name, "", "")
+		flags.SetAnnotation(name, SecretSourceAnnotation, []string{"access-key"})
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	return flags
+}
+
+func TestReadSecret(t *testing.T) {
+	test := func(t testing.TB, args []string, expected string) {
+		t.Helper()
+
+		flags := newSecretFlagSet(t, args)
+		actual, err := ReadSecret(flags, "access-key")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "access-key")
+
+	if err := os.WriteFile(path, []byte("file-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, []string{}, "")
+	test(t, []string{"--access-key", "secret"}, "secret")
+	test(t, []string{"--access-key-file", path}, "file-secret")
+
+	if runtime.GOOS != "windows" {
+		test(t, []string{"--access-key-command", "echo command-secret"}, "command-secret")
+	}
+}
+
+func TestReadSecretWithMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access-key")
+	flags := newSecretFlagSet(t, []string{"--access-key-file", path})
+
+	if _, err := ReadSecret(flags, "access-key"); err == nil {
+		t.Error("expected failure: missing file")
+	}
+}
+
+func TestReadSecretWithFailedCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires sh")
+	}
+
+	flags := newSecretFlagSet(t, []string{"--access-key-command", "exit 1"})
+
+	if _, err := ReadSecret(flags, "access-key"); err == nil {
+		t.Error("expected failure: command exited with a nonzero status")
+	}
+}
+
+func TestApplyProfileWithSecretSourceSet(t *testing.T) {
+	flags := newSecretFlagSet(t, []string{"--access-key-file", "-"})
+
+	profile := Profile{
+		"access-key":         "profile-secret",
+		"access-key-command": "echo profile-secret",
+	}
+
+	if err := ApplyProfile(flags, profile); err != nil {
+		t.Fatal(err)
+	}
+
+	if flags.Changed("access-key") || flags.Changed("access-key-command") {
+		t.Error("expected profile secret sources to be skipped")
+	}
+}
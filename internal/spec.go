@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// SubmitSpec is a declarative description of a workflow submission.
+//
+// Each field corresponds to a `submit` flag. Unset fields are left to the
+// other flag sources. String values can reference environment variables
+// using `${NAME}`.
+//
+// The region is the service region (`--region`), which selects the base URL.
+type SubmitSpec struct {
+	Process           SubmitSpecProcess      `yaml:"process,omitempty" json:"process,omitempty"`
+	Description       *string                `yaml:"description,omitempty" json:"description,omitempty"`
+	Input             SubmitSpecInput        `yaml:"input,omitempty" json:"input,omitempty"`
+	Output            SubmitSpecOutput       `yaml:"output,omitempty" json:"output,omitempty"`
+	OptionalArgs      SubmitSpecOptionalArgs `yaml:"optionalArgs,omitempty" json:"optionalArgs,omitempty"`
+	Region            *string                `yaml:"region,omitempty" json:"region,omitempty"`
+	IgnoreAzureRegion *bool                  `yaml:"ignoreAzureRegion,omitempty" json:"ignoreAzureRegion,omitempty"`
+}
+
+type SubmitSpecProcess struct {
+	Name *string `yaml:"name,omitempty" json:"name,omitempty"`
+	Args *string `yaml:"args,omitempty" json:"args,omitempty"`
+}
+
+type SubmitSpecStorage struct {
+	ConnectionString        *string `yaml:"connectionString,omitempty" json:"connectionString,omitempty"`
+	ConnectionStringFile    *string `yaml:"connectionStringFile,omitempty" json:"connectionStringFile,omitempty"`
+	ConnectionStringCommand *string `yaml:"connectionStringCommand,omitempty" json:"connectionStringCommand,omitempty"`
+	Auth                    *string `yaml:"auth,omitempty" json:"auth,omitempty"`
+	Region                  *string `yaml:"region,omitempty" json:"region,omitempty"`
+	ContainerName           *string `yaml:"containerName,omitempty" json:"containerName,omitempty"`
+}
+
+type SubmitSpecInput struct {
+	Storage  SubmitSpecStorage `yaml:"storage,omitempty" json:"storage,omitempty"`
+	BlobName *string           `yaml:"blobName,omitempty" json:"blobName,omitempty"`
+}
+
+type SubmitSpecOutput struct {
+	Storage    SubmitSpecStorage `yaml:"storage,omitempty" json:"storage,omitempty"`
+	Basename   *string           `yaml:"basename,omitempty" json:"basename,omitempty"`
+	Overwrite  *bool             `yaml:"overwrite,omitempty" json:"overwrite,omitempty"`
+	IncludeLog *bool             `yaml:"includeLog,omitempty" json:"includeLog,omitempty"`
+}
+
+type SubmitSpecOptionalArgs struct {
+	EmitRefConfidence *string `yaml:"emitRefConfidence,omitempty" json:"emitRefConfidence,omitempty"`
+	BgzipOutput       *bool   `yaml:"bgzipOutput,omitempty" json:"bgzipOutput,omitempty"`
+}
+
+// specStringFields returns the string fields of the spec keyed by flag name.
+func (s *SubmitSpec) specStringFields() map[string]**string {
+	return map[string]**string{
+		"process-name":                             &s.Process.Name,
+		"process-args":                             &s.Process.Args,
+		"description":                              &s.Description,
+		"input-storage-connection-string":          &s.Input.Storage.ConnectionString,
+		"input-storage-connection-string-file":     &s.Input.Storage.ConnectionStringFile,
+		"input-storage-connection-string-command":  &s.Input.Storage.ConnectionStringCommand,
+		"input-storage-auth":                       &s.Input.Storage.Auth,
+		"input-storage-region":                     &s.Input.Storage.Region,
+		"input-storage-container-name":             &s.Input.Storage.ContainerName,
+		"input-blob-name":                          &s.Input.BlobName,
+		"output-storage-connection-string":         &s.Output.Storage.ConnectionString,
+		"output-storage-connection-string-file":    &s.Output.Storage.ConnectionStringFile,
+		"output-storage-connection-string-command": &s.Output.Storage.ConnectionStringCommand,
+		"output-storage-auth":                      &s.Output.Storage.Auth,
+		"output-storage-region":                    &s.Output.Storage.Region,
+		"output-storage-container-name":            &s.Output.Storage.ContainerName,
+		"output-basename":                          &s.Output.Basename,
+		"emit-ref-confidence":                      &s.OptionalArgs.EmitRefConfidence,
+		"region":                                   &s.Region,
+	}
+}
+
+// specBoolFields returns the boolean fields of the spec keyed by flag name.
+func (s *SubmitSpec) specBoolFields() map[string]**bool {
+	return map[string]**bool{
+		"output-overwrite":    &s.Output.Overwrite,
+		"output-include-log":  &s.Output.IncludeLog,
+		"bgzip-output":        &s.OptionalArgs.BgzipOutput,
+		"ignore-azure-region": &s.IgnoreAzureRegion,
+	}
+}
+
+// secretSpecFields are the secret spec fields. Their values are never
+// emitted by `SubmitSpecFromFlags`, only their sources.
+var secretSpecFields = []string{
+	"input-storage-connection-string",
+	"output-storage-connection-string",
+}
+
+var envReferencePattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// LoadSubmitSpec reads a submit spec from a YAML or JSON (`.json`) file and
+// expands environment variable references (`${NAME}`) in its string values.
+// Relative connection string files are resolved against the directory of the
+// spec file.
+//
+// Unknown fields and undefined environment variables are errors.
+func LoadSubmitSpec(path string) (SubmitSpec, error) {
+	spec := SubmitSpec{}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		return spec, err
+	}
+
+	if err := decodeSubmitSpec(path, data, &spec); err != nil {
+		return spec, fmt.Errorf("invalid spec: %s: %w", path, err)
+	}
+
+	for name, field := range spec.specStringFields() {
+		if *field == nil {
+			continue
+		}
+
+		value, err := expandEnv(**field)
+
+		if err != nil {
+			return spec, fmt.Errorf("invalid spec: %s: %s: %w", path, name, err)
+		}
+
+		*field = &value
+	}
+
+	for _, field := range []*string{spec.Input.Storage.ConnectionStringFile, spec.Output.Storage.ConnectionStringFile} {
+		if field != nil && *field != stdinPath && len(*field) > 0 && !filepath.IsAbs(*field) {
+			*field = filepath.Join(filepath.Dir(path), *field)
+		}
+	}
+
+	return spec, nil
+}
+
+// decodeSubmitSpec decodes JSON if the path has a `.json` extension and YAML
+// otherwise.
+func decodeSubmitSpec(path string, data []byte, spec *SubmitSpec) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(spec)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(spec); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+
+	return nil
+}
+
+// ApplySubmitSpec sets each flag that was not explicitly given on the command
+// line to its value in the spec, if present.
+func ApplySubmitSpec(flags *pflag.FlagSet, spec SubmitSpec) error {
+	values := Profile{}
+
+	for name, field := range spec.specStringFields() {
+		if *field != nil {
+			values[name] = **field
+		}
+	}
+
+	for name, field := range spec.specBoolFields() {
+		if *field != nil {
+			values[name] = strconv.FormatBool(**field)
+		}
+	}
+
+	return applyFlagSource(flags, func(name string) (string, string, bool) {
+		value, ok := values[name]
+		return fmt.Sprintf("spec value for %s", name), value, ok
+	})
+}
+
+// SubmitSpecFromFlags returns the effective submit spec from the flags.
+//
+// Secrets are not included. A connection string read from a file or command
+// is written as that source, with the file as an absolute path, e.g.,
+// `connectionStringFile: /secrets/input.txt`. A connection string given as a
+// value is written as a reference to its bound environment variable, e.g.,
+// `${MSGEN_INPUT_STORAGE_CONNECTION_STRING}`, if that variable is set to it,
+// and omitted otherwise, e.g., if it is from a profile. The region is omitted
+// if it is unknown.
+func SubmitSpecFromFlags(flags *pflag.FlagSet) (SubmitSpec, error) {
+	spec := SubmitSpec{}
+
+	secretSources := map[string]bool{}
+
+	for _, name := range secretSpecFields {
+		for _, source := range SecretFlagNames(name) {
+			secretSources[source] = true
+		}
+	}
+
+	fields := spec.specStringFields()
+
+	for name, field := range fields {
+		if secretSources[name] {
+			continue
+		}
+
+		value, err := flags.GetString(name)
+
+		if err != nil {
+			return spec, err
+		}
+
+		*field = &value
+	}
+
+	if len(*spec.Region) == 0 {
+		spec.Region = nil
+	}
+
+	for _, name := range secretSpecFields {
+		names := SecretFlagNames(name)
+
+		if value := lookupString(flags, names[0]); len(value) > 0 {
+			if envValue, ok := os.LookupEnv(EnvVarName(name)); ok && envValue == value {
+				reference := fmt.Sprintf("${%s}", EnvVarName(name))
+				*fields[name] = &reference
+			}
+		}
+
+		if path := lookupString(flags, names[1]); len(path) > 0 {
+			if path != stdinPath {
+				absPath, err := filepath.Abs(path)
+
+				if err != nil {
+					return spec, err
+				}
+
+				path = absPath
+			}
+
+			*fields[names[1]] = &path
+		}
+
+		if command := lookupString(flags, names[2]); len(command) > 0 {
+			*fields[names[2]] = &command
+		}
+	}
+
+	for name, field := range spec.specBoolFields() {
+		value, err := flags.GetBool(name)
+
+		if err != nil {
+			return spec, err
+		}
+
+		*field = &value
+	}
+
+	return spec, nil
+}
+
+// WriteSubmitSpec writes the spec as YAML.
+func WriteSubmitSpec(w io.Writer, spec SubmitSpec) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(&spec); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func expandEnv(s string) (string, error) {
+	errs := []error{}
+
+	expanded := envReferencePattern.ReplaceAllStringFunc(s, func(reference string) string {
+		name := envReferencePattern.FindStringSubmatch(reference)[1]
+		value, ok := os.LookupEnv(name)
+
+		if !ok {
+			errs = append(errs, fmt.Errorf("undefined environment variable: %s", name))
+		}
+
+		return value
+	})
+
+	return expanded, errors.Join(errs...)
+}
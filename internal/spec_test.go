@@ -0,0 +1,305 @@
+package internal
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/pflag"
+)
+
+func newSubmitSpecFlagSet() *pflag.FlagSet {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+	flags.String("process-name", "", "")
+	flags.String("process-args", "", "")
+	flags.String("input-storage-connection-string", "", "")
+	flags.String("input-storage-auth", StorageAuthModeSharedKey, "")
+	flags.String("input-storage-region", "", "")
+	flags.String("input-storage-container-name", "", "")
+	flags.String("input-blob-name", "", "")
+	flags.String("description", "", "")
+	flags.String("output-storage-connection-string", "", "")
+	flags.String("output-storage-auth", StorageAuthModeSharedKey, "")
+	flags.String("output-storage-region", "", "")
+	flags.String("output-storage-container-name", "", "")
+	flags.String("output-basename", "", "")
+	flags.Bool("output-overwrite", false, "")
+	flags.Bool("output-include-log", true, "")
+	flags.String("emit-ref-confidence", ReferenceConfidenceModeNone, "")
+	flags.Bool("bgzip-output", false, "")
+	flags.Bool("ignore-azure-region", false, "")
+	flags.String("region", "", "")
+
+	for _, name := range secretSpecFields {
+		for _, source := range SecretFlagNames(name)[1:] {
+			flags.String(source, "", "")
+		}
+	}
+
+	return flags
+}
+
+func writeSpec(t testing.TB, name string, data string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestLoadSubmitSpec(t *testing.T) {
+	t.Setenv("MSGEN_TEST_CONNECTION_STRING", "AccountName=input;AccountKey=secret;")
+
+	yamlPath := writeSpec(t, "spec.yaml", `process:
+  name: snapgatk-20190409_1
+  args: R=hg38m1x
+input:
+  storage:
+    connectionString: ${MSGEN_TEST_CONNECTION_STRING}
+    containerName: data
+  blobName: sample.bam
+output:
+  overwrite: true
+`)
+
+	jsonPath := writeSpec(t, "spec.json", `{
+	"process": {"name": "snapgatk-20190409_1", "args": "R=hg38m1x"},
+	"input": {
+		"storage": {
+			"connectionString": "${MSGEN_TEST_CONNECTION_STRING}",
+			"containerName": "data"
+		},
+		"blobName": "sample.bam"
+	},
+	"output": {"overwrite": true}
+}`)
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		spec, err := LoadSubmitSpec(path)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := *spec.Process.Name; actual != "snapgatk-20190409_1" {
+			t.Errorf("%s: expected process name, got %q", path, actual)
+		}
+
+		if actual := *spec.Input.Storage.ConnectionString; actual != "AccountName=input;AccountKey=secret;" {
+			t.Errorf("%s: expected expanded connection string, got %q", path, actual)
+		}
+
+		if !*spec.Output.Overwrite {
+			t.Errorf("%s: expected overwrite", path)
+		}
+
+		if spec.Output.Basename != nil {
+			t.Errorf("%s: expected unset basename", path)
+		}
+	}
+}
+
+func TestLoadSubmitSpecWithInvalidInput(t *testing.T) {
+	test := func(t testing.TB, name string, data string) {
+		t.Helper()
+
+		path := writeSpec(t, name, data)
+
+		if _, err := LoadSubmitSpec(path); err == nil {
+			t.Errorf("expected failure: %s", data)
+		}
+	}
+
+	test(t, "unknown.yaml", "process:\n  image: snapgatk\n")
+	test(t, "unknown.json", `{"location": "eastus"}`)
+	test(t, "env.yaml", "description: ${MSGEN_TEST_UNDEFINED}\n")
+}
+
+func TestApplySubmitSpec(t *testing.T) {
+	flags := newSubmitSpecFlagSet()
+
+	args := []string{
+		"--description", "flag",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	processName := "snapgatk-20190409_1"
+	description := "spec"
+	bgzipOutput := true
+	region := "westus2"
+	connectionStringCommand := "cat input.txt"
+
+	spec := SubmitSpec{
+		Process:      SubmitSpecProcess{Name: &processName},
+		Description:  &description,
+		Input:        SubmitSpecInput{Storage: SubmitSpecStorage{ConnectionStringCommand: &connectionStringCommand}},
+		OptionalArgs: SubmitSpecOptionalArgs{BgzipOutput: &bgzipOutput},
+		Region:       &region,
+	}
+
+	if err := ApplySubmitSpec(flags, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	test := func(t testing.TB, name string, expected string) {
+		t.Helper()
+
+		actual := flags.Lookup(name).Value.String()
+
+		if actual != expected {
+			t.Errorf("%s: expected %q, got %q", name, expected, actual)
+		}
+	}
+
+	test(t, "process-name", processName)
+	test(t, "description", "flag")
+	test(t, "bgzip-output", "true")
+	test(t, "output-include-log", "true")
+	test(t, "region", region)
+	test(t, "input-storage-connection-string-command", connectionStringCommand)
+}
+
+func TestSubmitSpecFromFlags(t *testing.T) {
+	t.Setenv("MSGEN_INPUT_STORAGE_CONNECTION_STRING", "AccountName=input;AccountKey=input-secret;")
+
+	outputPath, err := filepath.Abs("output.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	flags := newSubmitSpecFlagSet()
+
+	args := []string{
+		"--process-name", "snapgatk-20190409_1",
+		"--input-storage-connection-string", "AccountName=input;AccountKey=input-secret;",
+		"--output-storage-connection-string-file", "output.txt",
+		"--output-overwrite",
+		"--region", "westus2",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := SubmitSpecFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteSubmitSpec(&buf, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	actual := buf.String()
+
+	if strings.Contains(actual, "secret") {
+		t.Errorf("spec contains a secret:\n%s", actual)
+	}
+
+	for _, expected := range []string{
+		"name: snapgatk-20190409_1",
+		"connectionString: ${MSGEN_INPUT_STORAGE_CONNECTION_STRING}",
+		"connectionStringFile: " + outputPath,
+		"overwrite: true",
+		"includeLog: true",
+		"region: westus2",
+	} {
+		if !strings.Contains(actual, expected) {
+			t.Errorf("expected spec to contain %q:\n%s", expected, actual)
+		}
+	}
+
+	for _, unexpected := range []string{
+		"${MSGEN_OUTPUT_STORAGE_CONNECTION_STRING}",
+		"connectionStringCommand",
+	} {
+		if strings.Contains(actual, unexpected) {
+			t.Errorf("expected spec to not contain %q:\n%s", unexpected, actual)
+		}
+	}
+}
+
+func TestSubmitSpecFromFlagsRoundTrip(t *testing.T) {
+	flags := newSubmitSpecFlagSet()
+
+	args := []string{
+		"--process-name", "snapgatk-20190409_1",
+		"--input-storage-connection-string", "AccountName=input;AccountKey=input-secret;",
+		"--output-storage-connection-string-file", "output.txt",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	spec, err := SubmitSpecFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if spec.Input.Storage.ConnectionString != nil {
+		t.Errorf("expected no input connection string without its environment variable, got %q", *spec.Input.Storage.ConnectionString)
+	}
+
+	var buf bytes.Buffer
+
+	if err := WriteSubmitSpec(&buf, spec); err != nil {
+		t.Fatal(err)
+	}
+
+	loadedSpec, err := LoadSubmitSpec(writeSpec(t, "spec.yaml", buf.String()))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := filepath.Abs("output.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if actual := *loadedSpec.Output.Storage.ConnectionStringFile; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestLoadSubmitSpecResolvesConnectionStringFile(t *testing.T) {
+	path := writeSpec(t, "spec.yaml", `input:
+  storage:
+    connectionStringFile: secrets/input.txt
+output:
+  storage:
+    connectionStringFile: "-"
+`)
+
+	spec, err := LoadSubmitSpec(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := filepath.Join(filepath.Dir(path), "secrets", "input.txt")
+
+	if actual := *spec.Input.Storage.ConnectionStringFile; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if actual := *spec.Output.Storage.ConnectionStringFile; actual != "-" {
+		t.Errorf("expected stdin, got %q", actual)
+	}
+}
@@ -1,5 +1,7 @@
 package internal
 
+import "fmt"
+
 type Status int
 
 const (
@@ -11,6 +13,8 @@ const (
 	StatusCancelled  = 60000
 )
 
+// String returns the status name, or `unknown(<n>)` for a status that is not
+// known, e.g., one added by a newer version of the service.
 func (s Status) String() string {
 	switch s {
 	case StatusQueued:
@@ -26,6 +30,47 @@ func (s Status) String() string {
 	case StatusCancelled:
 		return "cancelled"
 	default:
-		panic("internal error: entered unreachable code")
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// IsKnown returns whether the status is one of the known statuses.
+func (s Status) IsKnown() bool {
+	switch s {
+	case StatusQueued, StatusWorking, StatusSuccess, StatusFailed, StatusCancelling, StatusCancelled:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsTerminal returns whether a workflow with the status has completed, i.e.,
+// succeeded, failed, or was cancelled. Unknown statuses are not terminal, as
+// the workflow may still be running.
+func (s Status) IsTerminal() bool {
+	return s == StatusSuccess || s == StatusFailed || s == StatusCancelled
+}
+
+// StatusNames are the names of the statuses, in the order of the workflow
+// lifecycle.
+var StatusNames = []string{"queued", "working", "success", "failed", "cancelling", "cancelled"}
+
+// ParseStatus parses a status name, e.g., `failed`.
+func ParseStatus(s string) (Status, error) {
+	switch s {
+	case "queued":
+		return StatusQueued, nil
+	case "working":
+		return StatusWorking, nil
+	case "success":
+		return StatusSuccess, nil
+	case "failed":
+		return StatusFailed, nil
+	case "cancelling":
+		return StatusCancelling, nil
+	case "cancelled":
+		return StatusCancelled, nil
+	default:
+		return 0, fmt.Errorf("invalid status: %q", s)
 	}
 }
@@ -19,4 +19,45 @@ func TestStatusString(t *testing.T) {
 	test(t, StatusFailed, "failed")
 	test(t, StatusCancelling, "cancelling")
 	test(t, StatusCancelled, "cancelled")
+	test(t, Status(70000), "unknown(70000)")
+}
+
+func TestStatusIsTerminal(t *testing.T) {
+	test := func(t testing.TB, status Status, expectedKnown bool, expectedTerminal bool) {
+		t.Helper()
+
+		if actual := status.IsKnown(); actual != expectedKnown {
+			t.Errorf("%s: expected known = %v, got %v", status, expectedKnown, actual)
+		}
+
+		if actual := status.IsTerminal(); actual != expectedTerminal {
+			t.Errorf("%s: expected terminal = %v, got %v", status, expectedTerminal, actual)
+		}
+	}
+
+	test(t, StatusQueued, true, false)
+	test(t, StatusWorking, true, false)
+	test(t, StatusSuccess, true, true)
+	test(t, StatusFailed, true, true)
+	test(t, StatusCancelling, true, false)
+	test(t, StatusCancelled, true, true)
+	test(t, Status(70000), false, false)
+}
+
+func TestParseStatus(t *testing.T) {
+	for _, name := range StatusNames {
+		status, err := ParseStatus(name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if status.String() != name {
+			t.Errorf("expected %q, got %q", name, status.String())
+		}
+	}
+
+	if _, err := ParseStatus("done"); err == nil {
+		t.Error("expected error for an invalid status")
+	}
 }
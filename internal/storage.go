@@ -1,26 +1,113 @@
 package internal
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 const sasLifetime = 72 * time.Hour
 
 type BlobServiceClient struct {
 	credential *azblob.SharedKeyCredential
+	serviceURL string
+
+	// serviceClient requests user delegation keys when authenticating with a
+	// Microsoft Entra ID token.
+	serviceClient            *service.Client
+	userDelegationCredential *service.UserDelegationCredential
+
+	// sharedAccessSignature is a SAS given in the connection string. It is
+	// used as is when there is no credential to sign new ones.
+	sharedAccessSignature string
 }
 
+// NewBlobServiceClient returns a client for the storage account at its
+// default Azure public cloud endpoint.
 func NewBlobServiceClient(accountName string, accountKey string) (BlobServiceClient, error) {
-	client := BlobServiceClient{}
+	connectionString := ConnectionString{AccountName: accountName, AccountKey: accountKey}
+
+	return NewBlobServiceClientFromConfig(StorageConfig{
+		AccountName:  accountName,
+		AccountKey:   accountKey,
+		BlobEndpoint: connectionString.BlobServiceURL(),
+	})
+}
+
+// BlobServiceClientOptions configures a `BlobServiceClient`.
+type BlobServiceClientOptions struct {
+	// Transport sends requests to Azure Storage and Microsoft Entra ID. If
+	// nil, the Azure SDK default is used.
+	Transport http.RoundTripper
+}
+
+// azcoreClientOptions returns the Azure SDK client options.
+func (o BlobServiceClientOptions) azcoreClientOptions() azcore.ClientOptions {
+	options := azcore.ClientOptions{}
+
+	if o.Transport != nil {
+		options.Transport = &http.Client{Transport: o.Transport}
+	}
+
+	return options
+}
+
+// NewBlobServiceClientFromConfig returns a client for the storage account in
+// the configuration, using its blob endpoint and auth mode.
+//
+// With a token-based auth mode, SAS are signed using a user delegation key.
+// Otherwise, the account key signs SAS, or without one, the shared access
+// signature from the connection string is used as is.
+func NewBlobServiceClientFromConfig(config StorageConfig) (BlobServiceClient, error) {
+	return NewBlobServiceClientWithOptions(config, BlobServiceClientOptions{})
+}
+
+// NewBlobServiceClientWithOptions is like `NewBlobServiceClientFromConfig` but
+// sends requests, if any, with the given options.
+func NewBlobServiceClientWithOptions(config StorageConfig, options BlobServiceClientOptions) (BlobServiceClient, error) {
+	client := BlobServiceClient{
+		serviceURL:            config.BlobEndpoint,
+		sharedAccessSignature: config.SharedAccessSignature,
+	}
+
+	if config.Auth.UsesTokenCredential() {
+		clientOptions := options.azcoreClientOptions()
+		tokenCredential, err := newTokenCredential(config.Auth, clientOptions)
+
+		if err != nil {
+			return client, err
+		}
 
-	credential, err := azblob.NewSharedKeyCredential(accountName, accountKey)
+		return newUserDelegationBlobServiceClient(
+			config.BlobEndpoint,
+			tokenCredential,
+			&service.ClientOptions{ClientOptions: clientOptions},
+		)
+	}
+
+	if len(config.AccountKey) == 0 {
+		if len(config.SharedAccessSignature) == 0 {
+			return client, errors.New("missing account key or shared access signature")
+		}
+
+		return client, nil
+	}
+
+	credential, err := azblob.NewSharedKeyCredential(config.AccountName, config.AccountKey)
 
 	if err != nil {
 		return client, err
@@ -31,10 +118,54 @@ func NewBlobServiceClient(accountName string, accountKey string) (BlobServiceCli
 	return client, nil
 }
 
+func newUserDelegationBlobServiceClient(
+	serviceURL string,
+	tokenCredential azcore.TokenCredential,
+	options *service.ClientOptions,
+) (BlobServiceClient, error) {
+	client := BlobServiceClient{serviceURL: serviceURL}
+
+	serviceClient, err := service.NewClient(serviceURL, tokenCredential, options)
+
+	if err != nil {
+		return client, err
+	}
+
+	client.serviceClient = serviceClient
+
+	return client, nil
+}
+
+// URL returns the blob service endpoint.
+func (c *BlobServiceClient) URL() string {
+	return c.serviceURL
+}
+
+// ContainerURL returns the URL of the container.
+func (c *BlobServiceClient) ContainerURL(containerName string) string {
+	return fmt.Sprintf("%s/%s", c.serviceURL, url.PathEscape(containerName))
+}
+
+// BlobURL returns the URL of the blob.
+func (c *BlobServiceClient) BlobURL(containerName string, blobName string) string {
+	return fmt.Sprintf("%s/%s", c.ContainerURL(containerName), escapeBlobName(blobName))
+}
+
 func (c *BlobServiceClient) GenerateBlobSAS(
 	containerName string,
 	blobName string,
 	permissions sas.BlobPermissions,
+) (string, error) {
+	return c.GenerateBlobSASContext(context.Background(), containerName, blobName, permissions)
+}
+
+// GenerateBlobSASContext is like `GenerateBlobSAS` but stops when the context
+// is done while requesting a user delegation key.
+func (c *BlobServiceClient) GenerateBlobSASContext(
+	ctx context.Context,
+	containerName string,
+	blobName string,
+	permissions sas.BlobPermissions,
 ) (string, error) {
 	now := time.Now().UTC()
 	expiryTime := now.Add(sasLifetime)
@@ -47,24 +178,22 @@ func (c *BlobServiceClient) GenerateBlobSAS(
 		Permissions:   permissions.String(),
 	}
 
-	queryParams, err := values.SignWithSharedKey(c.credential)
-
-	if err != nil {
-		return "", err
-	}
-
-	sas, err := encodeOrdered(&queryParams)
-
-	if err != nil {
-		return "", err
-	}
-
-	return sas, nil
+	return c.sign(ctx, values)
 }
 
 func (c *BlobServiceClient) GenerateContainerSAS(
 	containerName string,
 	permissions sas.ContainerPermissions,
+) (string, error) {
+	return c.GenerateContainerSASContext(context.Background(), containerName, permissions)
+}
+
+// GenerateContainerSASContext is like `GenerateContainerSAS` but stops when
+// the context is done while requesting a user delegation key.
+func (c *BlobServiceClient) GenerateContainerSASContext(
+	ctx context.Context,
+	containerName string,
+	permissions sas.ContainerPermissions,
 ) (string, error) {
 	now := time.Now().UTC()
 	expiryTime := now.Add(sasLifetime)
@@ -76,7 +205,37 @@ func (c *BlobServiceClient) GenerateContainerSAS(
 		Permissions:   permissions.String(),
 	}
 
-	queryParams, err := values.SignWithSharedKey(c.credential)
+	return c.sign(ctx, values)
+}
+
+// sign signs the SAS values with the account key or user delegation key and
+// encodes them with `encodeOrdered`.
+//
+// Signing is traced as a span, which includes the request for a user
+// delegation key, if any.
+func (c *BlobServiceClient) sign(ctx context.Context, values sas.BlobSignatureValues) (_ string, err error) {
+	ctx, span := StartSpan(
+		ctx,
+		"sign SAS",
+		attribute.String("azure.storage.service_url", c.serviceURL),
+		attribute.String("azure.storage.container", values.ContainerName),
+		attribute.String("azure.storage.blob", values.BlobName),
+		attribute.String("azure.storage.sas.permissions", values.Permissions),
+	)
+
+	defer func() { EndSpan(span, err) }()
+
+	if c.credential == nil && c.serviceClient == nil {
+		return orderSAS(c.sharedAccessSignature)
+	}
+
+	var queryParams sas.QueryParameters
+
+	if c.credential != nil {
+		queryParams, err = values.SignWithSharedKey(c.credential)
+	} else {
+		queryParams, err = c.signWithUserDelegation(ctx, values)
+	}
 
 	if err != nil {
 		return "", err
@@ -91,15 +250,65 @@ func (c *BlobServiceClient) GenerateContainerSAS(
 	return sas, nil
 }
 
+func (c *BlobServiceClient) signWithUserDelegation(
+	ctx context.Context,
+	values sas.BlobSignatureValues,
+) (sas.QueryParameters, error) {
+	userDelegationCredential, err := c.getUserDelegationCredential(ctx, values.StartTime, values.ExpiryTime)
+
+	if err != nil {
+		return sas.QueryParameters{}, err
+	}
+
+	return values.SignWithUserDelegation(userDelegationCredential)
+}
+
+// getUserDelegationCredential requests a user delegation key valid for the
+// given period. The key is reused for subsequent SAS.
+func (c *BlobServiceClient) getUserDelegationCredential(
+	ctx context.Context,
+	startTime time.Time,
+	expiryTime time.Time,
+) (*service.UserDelegationCredential, error) {
+	if c.userDelegationCredential != nil {
+		return c.userDelegationCredential, nil
+	}
+
+	info := service.KeyInfo{
+		Start:  to.Ptr(startTime.UTC().Format(sas.TimeFormat)),
+		Expiry: to.Ptr(expiryTime.UTC().Format(sas.TimeFormat)),
+	}
+
+	userDelegationCredential, err := c.serviceClient.GetUserDelegationCredential(
+		ctx,
+		info,
+		nil,
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get user delegation key: %w", err)
+	}
+
+	c.userDelegationCredential = userDelegationCredential
+
+	return userDelegationCredential, nil
+}
+
 // encodeOrdered encodes the SAS query parameters with the `signedversion`
 // (`sv`) key first.
 //
 // Microsoft Genomics requires the `signedversion` key to be first in the SAS;
 // otherwise, the service mysteriously replies with an HTTP 500.
 func encodeOrdered(p *sas.QueryParameters) (string, error) {
+	return orderSAS(p.Encode())
+}
+
+// orderSAS reorders the encoded SAS with the `signedversion` (`sv`) key first.
+// See `encodeOrdered`.
+func orderSAS(s string) (string, error) {
 	const signedVersionKey = "sv"
 
-	values, err := url.ParseQuery(p.Encode())
+	values, err := url.ParseQuery(strings.TrimPrefix(s, "?"))
 
 	if err != nil {
 		return "", err
@@ -124,11 +333,69 @@ func encodeOrdered(p *sas.QueryParameters) (string, error) {
 	return buf.String(), nil
 }
 
+// escapeBlobName escapes each path segment of the blob name.
+func escapeBlobName(blobName string) string {
+	segments := strings.Split(blobName, "/")
+
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// Azurite (Azure Storage emulator) well-known account and endpoint, used by
+// `UseDevelopmentStorage=true`.
+//
+// https://learn.microsoft.com/en-us/azure/storage/common/storage-use-azurite#connection-strings
+const (
+	developmentStorageAccountName = "devstoreaccount1"
+	developmentStorageAccountKey  = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	developmentStorageProxyURI    = "http://127.0.0.1"
+	developmentStorageBlobPort    = "10000"
+)
+
+const (
+	defaultEndpointsProtocol = "https"
+	defaultEndpointSuffix    = "core.windows.net"
+)
+
+// ConnectionString is an Azure Storage connection string.
+//
+// https://learn.microsoft.com/en-us/azure/storage/common/storage-configure-connection-string
 type ConnectionString struct {
-	AccountName string
-	AccountKey  string
+	AccountName                string
+	AccountKey                 string
+	DefaultEndpointsProtocol   string
+	EndpointSuffix             string
+	BlobEndpoint               string
+	QueueEndpoint              string
+	TableEndpoint              string
+	FileEndpoint               string
+	SharedAccessSignature      string
+	UseDevelopmentStorage      bool
+	DevelopmentStorageProxyURI string
 }
 
+// LogValue masks the account key and SAS.
+func (c ConnectionString) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("accountName", c.AccountName),
+		slog.String("accountKey", maskNonEmpty(c.AccountKey)),
+		slog.String("sharedAccessSignature", maskNonEmpty(c.SharedAccessSignature)),
+		slog.String("blobEndpoint", c.BlobEndpoint),
+		slog.String("endpointSuffix", c.EndpointSuffix),
+		slog.Bool("useDevelopmentStorage", c.UseDevelopmentStorage),
+	)
+}
+
+// ParseConnectionString parses an Azure Storage connection string.
+//
+// Keys are case-insensitive. Unknown keys are ignored. When
+// `UseDevelopmentStorage=true`, the Azurite account name, account key, and
+// blob endpoint are used unless given explicitly. Without an `AccountName`,
+// the account name is taken from the `BlobEndpoint`, e.g., for a SAS
+// connection string (see `accountNameFromBlobEndpoint`).
 func ParseConnectionString(s string) (ConnectionString, error) {
 	const delimiter = ";"
 	const componentSeparator = "="
@@ -156,15 +423,130 @@ func ParseConnectionString(s string) (ConnectionString, error) {
 
 		value := components[1]
 
-		switch key {
-		case "AccountName":
+		switch strings.ToLower(key) {
+		case "accountname":
 			connectionString.AccountName = value
-		case "AccountKey":
+		case "accountkey":
 			connectionString.AccountKey = value
+		case "defaultendpointsprotocol":
+			connectionString.DefaultEndpointsProtocol = value
+		case "endpointsuffix":
+			connectionString.EndpointSuffix = value
+		case "blobendpoint":
+			connectionString.BlobEndpoint = value
+		case "queueendpoint":
+			connectionString.QueueEndpoint = value
+		case "tableendpoint":
+			connectionString.TableEndpoint = value
+		case "fileendpoint":
+			connectionString.FileEndpoint = value
+		case "sharedaccesssignature":
+			connectionString.SharedAccessSignature = value
+		case "usedevelopmentstorage":
+			useDevelopmentStorage, err := strconv.ParseBool(value)
+
+			if err != nil {
+				return connectionString, fmt.Errorf("invalid connection string: invalid %s value: %q", key, value)
+			}
+
+			connectionString.UseDevelopmentStorage = useDevelopmentStorage
+		case "developmentstorageproxyuri":
+			connectionString.DevelopmentStorageProxyURI = value
 		default:
 			continue
 		}
 	}
 
+	if connectionString.UseDevelopmentStorage {
+		applyDevelopmentStorageDefaults(&connectionString)
+	}
+
+	if len(connectionString.AccountName) == 0 {
+		connectionString.AccountName = accountNameFromBlobEndpoint(connectionString.BlobEndpoint)
+	}
+
 	return connectionString, nil
 }
+
+// accountNameFromBlobEndpoint returns the account name in the blob endpoint,
+// i.e., the first path segment of a path-style endpoint, e.g.,
+// `devstoreaccount1` in `http://127.0.0.1:10000/devstoreaccount1`, or
+// otherwise the first label of the host, e.g., `acct` in
+// `https://acct.blob.core.windows.net`. It is empty if there is none.
+func accountNameFromBlobEndpoint(endpoint string) string {
+	u, err := url.Parse(endpoint)
+
+	if err != nil {
+		return ""
+	}
+
+	if segment, _, _ := strings.Cut(strings.TrimPrefix(u.Path, "/"), "/"); len(segment) > 0 {
+		return segment
+	}
+
+	host := u.Hostname()
+
+	if net.ParseIP(host) != nil {
+		return ""
+	}
+
+	label, _, ok := strings.Cut(host, ".")
+
+	if !ok {
+		return ""
+	}
+
+	return label
+}
+
+func applyDevelopmentStorageDefaults(c *ConnectionString) {
+	if len(c.AccountName) == 0 {
+		c.AccountName = developmentStorageAccountName
+	}
+
+	if len(c.AccountKey) == 0 && len(c.SharedAccessSignature) == 0 {
+		c.AccountKey = developmentStorageAccountKey
+	}
+
+	if len(c.BlobEndpoint) == 0 {
+		proxyURI := c.DevelopmentStorageProxyURI
+
+		if len(proxyURI) == 0 {
+			proxyURI = developmentStorageProxyURI
+		}
+
+		c.BlobEndpoint = fmt.Sprintf(
+			"%s:%s/%s",
+			strings.TrimRight(proxyURI, "/"),
+			developmentStorageBlobPort,
+			c.AccountName,
+		)
+	}
+}
+
+// BlobServiceURL returns the blob service endpoint, either given explicitly
+// (`BlobEndpoint`) or built from the protocol, account name, and endpoint
+// suffix, e.g., `https://<account>.blob.core.windows.net`.
+func (c *ConnectionString) BlobServiceURL() string {
+	if len(c.BlobEndpoint) > 0 {
+		return strings.TrimRight(c.BlobEndpoint, "/")
+	}
+
+	if len(c.AccountName) == 0 {
+		return ""
+	}
+
+	protocol := c.DefaultEndpointsProtocol
+
+	if len(protocol) == 0 {
+		protocol = defaultEndpointsProtocol
+	}
+
+	suffix := c.EndpointSuffix
+
+	if len(suffix) == 0 {
+		suffix = defaultEndpointSuffix
+	}
+
+	return fmt.Sprintf("%s://%s.blob.%s", protocol, c.AccountName, suffix)
+}
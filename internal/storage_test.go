@@ -1,13 +1,19 @@
 package internal
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/service"
 	"github.com/google/go-cmp/cmp"
 )
 
@@ -123,11 +129,252 @@ func TestParseConnectionString(t *testing.T) {
 	}
 
 	expected := ConnectionString{
-		AccountName: "msgenctl",
-		AccountKey:  "secret",
+		AccountName:              "msgenctl",
+		AccountKey:               "secret",
+		DefaultEndpointsProtocol: "https",
 	}
 
 	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
 		t.Errorf("connection string mismatch (-actual, +expected):\n%s", diff)
 	}
 }
+
+func TestParseConnectionStringWithAllKeys(t *testing.T) {
+	s := strings.Join([]string{
+		"DefaultEndpointsProtocol=http",
+		"AccountName=msgenctl",
+		"accountkey=secret",
+		"EndpointSuffix=core.usgovcloudapi.net",
+		"BlobEndpoint=https://msgenctl.blob.example.com",
+		"QueueEndpoint=https://msgenctl.queue.example.com",
+		"TableEndpoint=https://msgenctl.table.example.com",
+		"FileEndpoint=https://msgenctl.file.example.com",
+		"SharedAccessSignature=sv=2021-06-08&ss=b&sig=secret",
+	}, ";")
+
+	actual, err := ParseConnectionString(s)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ConnectionString{
+		AccountName:              "msgenctl",
+		AccountKey:               "secret",
+		DefaultEndpointsProtocol: "http",
+		EndpointSuffix:           "core.usgovcloudapi.net",
+		BlobEndpoint:             "https://msgenctl.blob.example.com",
+		QueueEndpoint:            "https://msgenctl.queue.example.com",
+		TableEndpoint:            "https://msgenctl.table.example.com",
+		FileEndpoint:             "https://msgenctl.file.example.com",
+		SharedAccessSignature:    "sv=2021-06-08&ss=b&sig=secret",
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("connection string mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestParseConnectionStringWithDevelopmentStorage(t *testing.T) {
+	actual, err := ParseConnectionString("UseDevelopmentStorage=true")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := ConnectionString{
+		AccountName:           developmentStorageAccountName,
+		AccountKey:            developmentStorageAccountKey,
+		BlobEndpoint:          "http://127.0.0.1:10000/devstoreaccount1",
+		UseDevelopmentStorage: true,
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("connection string mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if _, err := ParseConnectionString("UseDevelopmentStorage=maybe"); err == nil {
+		t.Error(`expected failure: UseDevelopmentStorage = "maybe"`)
+	}
+}
+
+func TestParseConnectionStringWithoutAccountName(t *testing.T) {
+	test := func(t testing.TB, s string, expected string) {
+		t.Helper()
+
+		connectionString, err := ParseConnectionString(s)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := connectionString.AccountName; actual != expected {
+			t.Errorf("%s: expected %q, got %q", s, expected, actual)
+		}
+	}
+
+	test(t, "BlobEndpoint=https://msgenctl.blob.core.windows.net/;SharedAccessSignature=sv=2021-06-08&sig=secret", "msgenctl")
+	test(t, "BlobEndpoint=http://127.0.0.1:10000/devstoreaccount1;SharedAccessSignature=sv=2021-06-08&sig=secret", "devstoreaccount1")
+	test(t, "AccountName=explicit;BlobEndpoint=https://msgenctl.blob.core.windows.net", "explicit")
+	test(t, "BlobEndpoint=http://127.0.0.1:10000;SharedAccessSignature=sv=2021-06-08&sig=secret", "")
+	test(t, "BlobEndpoint=http://localhost:10000;SharedAccessSignature=sv=2021-06-08&sig=secret", "")
+	test(t, "SharedAccessSignature=sv=2021-06-08&sig=secret", "")
+}
+
+func TestConnectionStringBlobServiceURL(t *testing.T) {
+	test := func(t testing.TB, s string, expected string) {
+		t.Helper()
+
+		connectionString, err := ParseConnectionString(s)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		actual := connectionString.BlobServiceURL()
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+	}
+
+	test(t, "AccountName=msgenctl;AccountKey=secret", "https://msgenctl.blob.core.windows.net")
+	test(
+		t,
+		"AccountName=msgenctl;AccountKey=secret;EndpointSuffix=core.chinacloudapi.cn",
+		"https://msgenctl.blob.core.chinacloudapi.cn",
+	)
+	test(
+		t,
+		"AccountName=msgenctl;BlobEndpoint=http://localhost:10000/msgenctl/",
+		"http://localhost:10000/msgenctl",
+	)
+	test(
+		t,
+		"UseDevelopmentStorage=true;DevelopmentStorageProxyUri=http://azurite",
+		"http://azurite:10000/devstoreaccount1",
+	)
+}
+
+func TestBlobServiceClientWithSharedAccessSignature(t *testing.T) {
+	config := StorageConfig{
+		AccountName:           "msgenctl",
+		SharedAccessSignature: "?ss=b&sig=secret&sv=2021-06-08",
+		BlobEndpoint:          "https://msgenctl.blob.core.windows.net",
+	}
+
+	blobServiceClient, err := NewBlobServiceClientFromConfig(config)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual, err := blobServiceClient.GenerateBlobSAS("test", "in.bam", sas.BlobPermissions{Read: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "sv=2021-06-08&sig=secret&ss=b"
+
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if _, err := NewBlobServiceClientFromConfig(StorageConfig{AccountName: "msgenctl"}); err == nil {
+		t.Error("expected failure: missing credentials")
+	}
+}
+
+func TestBlobServiceClientBlobURL(t *testing.T) {
+	blobServiceClient, err := NewBlobServiceClient("msgenctl", "bXNnZW5jdGw=")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	actual := blobServiceClient.BlobURL("test", "samples/sample 1.bam")
+	expected := "https://msgenctl.blob.core.windows.net/test/samples/sample%201.bam"
+
+	if actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+type staticTokenCredential struct{}
+
+func (c staticTokenCredential) GetToken(
+	ctx context.Context,
+	options policy.TokenRequestOptions,
+) (azcore.AccessToken, error) {
+	return azcore.AccessToken{Token: "token", ExpiresOn: time.Now().Add(time.Hour)}, nil
+}
+
+func TestBlobServiceClientWithUserDelegation(t *testing.T) {
+	requests := 0
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests++
+
+		if r.Method != http.MethodPost || r.URL.Query().Get("comp") != "userdelegationkey" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+
+		if actual := r.Header.Get("Authorization"); actual != "Bearer token" {
+			t.Errorf("expected bearer token, got %q", actual)
+		}
+
+		rw.Header().Set("Content-Type", "application/xml")
+		rw.Write([]byte(`<?xml version="1.0" encoding="utf-8"?>
+<UserDelegationKey>
+  <SignedOid>00000000-0000-0000-0000-000000000001</SignedOid>
+  <SignedTid>00000000-0000-0000-0000-000000000002</SignedTid>
+  <SignedStart>2024-01-01T00:00:00Z</SignedStart>
+  <SignedExpiry>2024-01-04T00:00:00Z</SignedExpiry>
+  <SignedService>b</SignedService>
+  <SignedVersion>2021-06-08</SignedVersion>
+  <Value>bXNnZW5jdGw=</Value>
+</UserDelegationKey>`))
+	}))
+
+	defer server.Close()
+
+	options := service.ClientOptions{}
+	options.Transport = server.Client()
+
+	blobServiceClient, err := newUserDelegationBlobServiceClient(
+		server.URL,
+		staticTokenCredential{},
+		&options,
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 2; i++ {
+		rawSAS, err := blobServiceClient.GenerateBlobSAS("test", "in.bam", sas.BlobPermissions{Read: true})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !strings.HasPrefix(rawSAS, "sv=") {
+			t.Errorf("query missing starting `sv` key: %s", rawSAS)
+		}
+
+		values, err := url.ParseQuery(rawSAS)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if actual := values.Get("skoid"); actual != "00000000-0000-0000-0000-000000000001" {
+			t.Errorf("expected user delegation skoid, got %q", actual)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("expected 1 user delegation key request, got %d", requests)
+	}
+}
@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+)
+
+const (
+	submissionTokenPrefix = "msgenctl-submission:"
+	submissionTokenSize   = 12
+)
+
+var submissionTokenPattern = regexp.MustCompile(`\[msgenctl-submission:([0-9a-f]+)\]`)
+
+// newSubmissionToken returns a random token identifying a single submission.
+func newSubmissionToken() (string, error) {
+	b := make([]byte, submissionTokenSize)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// withSubmissionToken appends the submission token to the workflow
+// description, e.g., `sample1 [msgenctl-submission:<token>]`.
+//
+// The API has no idempotency key, so the description is the only field that
+// is returned as given and can carry the token.
+func withSubmissionToken(description string, token string) string {
+	tag := submissionTag(token)
+
+	if len(description) == 0 {
+		return tag
+	}
+
+	return fmt.Sprintf("%s %s", description, tag)
+}
+
+// submissionTag returns the token as it is appended to a description, e.g.,
+// `[msgenctl-submission:<token>]`.
+func submissionTag(token string) string {
+	return fmt.Sprintf("[%s%s]", submissionTokenPrefix, token)
+}
+
+// SubmissionToken returns the submission token in the workflow description,
+// if any.
+func SubmissionToken(description string) (string, bool) {
+	matches := submissionTokenPattern.FindStringSubmatch(description)
+
+	if matches == nil {
+		return "", false
+	}
+
+	return matches[1], true
+}
+
+// WithoutSubmissionToken returns the workflow description without its
+// submission token, if any, e.g., `sample1` for
+// `sample1 [msgenctl-submission:<token>]`.
+func WithoutSubmissionToken(description string) string {
+	return strings.TrimSpace(submissionTokenPattern.ReplaceAllString(description, ""))
+}
+
+// hasSubmissionToken returns whether the description has the submission
+// token.
+func hasSubmissionToken(description string, token string) bool {
+	t, ok := SubmissionToken(description)
+	return ok && t == token
+}
+
+// submissionClockSkew is how long before the start of a submission a workflow
+// with its token may appear to be created, given the clocks of the client and
+// service differ.
+const submissionClockSkew = 15 * time.Minute
+
+// findWorkflowBySubmissionToken returns the most recent workflow with the
+// submission token created since the submission started.
+//
+// The workflows are queried by the token and creation date, newest first, so
+// only the matching workflow is downloaded if the API supports the query.
+func findWorkflowBySubmissionToken(ctx context.Context, client Client, token string, since time.Time) (Workflow, bool, error) {
+	options := WorkflowListOptions{
+		Filter: WorkflowFilter{
+			Since:           since.Add(-submissionClockSkew),
+			submissionToken: token,
+		},
+		Sort:    WorkflowSortKeyCreated,
+		Reverse: true,
+		Limit:   1,
+	}
+
+	workflows, err := ListWorkflowsContext(ctx, client, options)
+
+	if err != nil {
+		return Workflow{}, false, err
+	}
+
+	if len(workflows) == 0 {
+		return Workflow{}, false, nil
+	}
+
+	return workflows[0], true, nil
+}
+
+// isRetryableSubmitError returns whether a failed submission may be retried,
+// i.e., the error is transient or has one of the retryable status codes.
+//
+// Only the end of the submission context is terminal. A request timeout
+// (`ClientOptions.RequestTimeout`) also matches `context.DeadlineExceeded`,
+// but the workflow may have been created, so it is retried after checking
+// for it.
+func isRetryableSubmitError(ctx context.Context, err error, statusCodes []int) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var apiErr *APIError
+
+	if errors.As(err, &apiErr) {
+		return slices.Contains(statusCodes, apiErr.StatusCode)
+	}
+
+	// Transport errors, except those that will not succeed on retry, e.g.,
+	// invalid certificates.
+	retry, _ := retryablehttp.DefaultRetryPolicy(ctx, nil, err)
+
+	return retry
+}
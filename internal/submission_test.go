@@ -0,0 +1,277 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithSubmissionToken(t *testing.T) {
+	test := func(t testing.TB, description string, expected string) {
+		actual := withSubmissionToken(description, "0123abcd")
+
+		if actual != expected {
+			t.Errorf("expected %q, got %q", expected, actual)
+		}
+
+		token, ok := SubmissionToken(actual)
+
+		if !ok || token != "0123abcd" {
+			t.Errorf("expected token %q, got %q (ok = %v)", "0123abcd", token, ok)
+		}
+
+		if stripped := WithoutSubmissionToken(actual); stripped != description {
+			t.Errorf("expected %q without the token, got %q", description, stripped)
+		}
+	}
+
+	test(t, "sample", "sample [msgenctl-submission:0123abcd]")
+	test(t, "", "[msgenctl-submission:0123abcd]")
+
+	if _, ok := SubmissionToken("sample"); ok {
+		t.Error("expected no token")
+	}
+}
+
+func newSubmitTestConfig(baseURL string) SubmitConfig {
+	storage := StorageConfig{
+		AccountName:   "msgenctl",
+		AccountKey:    "bXNnZW5jdGw=",
+		ContainerName: "data",
+	}
+
+	return SubmitConfig{
+		Service:     ServiceConfig{BaseURL: baseURL, AccessKey: "secret"},
+		Input:       InputConfig{Storage: storage, BlobName: "sample.bam"},
+		Process:     ProcessConfig{Name: "snapgatk-20190409_1"},
+		Description: "sample",
+		Output:      OutputConfig{Storage: storage},
+	}
+}
+
+// newSubmitTestServer returns a server that fails the first `failures` POST
+// requests with an HTTP 503, or by not responding for `delay`, if set. If
+// `created` is set, the failed requests still create a workflow.
+func newSubmitTestServer(t testing.TB, failures int, created bool, delay time.Duration) (*httptest.Server, func() int) {
+	var mu sync.Mutex
+	workflows := []Workflow{}
+	posts := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.Method {
+		case http.MethodGet:
+			json.NewEncoder(rw).Encode(workflows)
+		case http.MethodPost:
+			posts++
+
+			newWorkflow := NewWorkflow{}
+
+			if err := json.NewDecoder(r.Body).Decode(&newWorkflow); err != nil {
+				t.Error(err)
+			}
+
+			workflow := Workflow{
+				ID:          WorkflowID(len(workflows) + 1),
+				Status:      StatusQueued,
+				Description: newWorkflow.Description,
+				CreatedDate: time.Now(),
+			}
+
+			if posts <= failures {
+				if created {
+					workflows = append(workflows, workflow)
+				}
+
+				if delay > 0 {
+					mu.Unlock()
+					time.Sleep(delay)
+					mu.Lock()
+					return
+				}
+
+				rw.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+
+			workflows = append(workflows, workflow)
+			json.NewEncoder(rw).Encode(workflow)
+		}
+	}))
+
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return posts
+	}
+}
+
+func newSubmitTestClient(baseURL string, submitRetryMax int) Client {
+	options := DefaultClientOptions()
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.SubmitRetryMax = submitRetryMax
+
+	return NewClientWithOptions(baseURL, "secret", options)
+}
+
+func TestSubmitWorkflowReturnsExistingWorkflowOnRetry(t *testing.T) {
+	server, posts := newSubmitTestServer(t, 1, true, 0)
+	defer server.Close()
+
+	client := newSubmitTestClient(server.URL, 3)
+	workflow, err := SubmitWorkflow(client, newSubmitTestConfig(server.URL))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if workflow.ID != 1 {
+		t.Errorf("expected workflow ID 1, got %v", workflow.ID)
+	}
+
+	if n := posts(); n != 1 {
+		t.Errorf("expected 1 POST request, got %d", n)
+	}
+}
+
+func TestSubmitWorkflowReturnsExistingWorkflowAfterRequestTimeout(t *testing.T) {
+	server, posts := newSubmitTestServer(t, 1, true, 200*time.Millisecond)
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+	options.RequestTimeout = 50 * time.Millisecond
+
+	client := NewClientWithOptions(server.URL, "secret", options)
+
+	workflow, err := SubmitWorkflow(client, newSubmitTestConfig(server.URL))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if workflow.ID != 1 {
+		t.Errorf("expected workflow ID 1, got %v", workflow.ID)
+	}
+
+	if n := posts(); n != 1 {
+		t.Errorf("expected 1 POST request, got %d", n)
+	}
+}
+
+func TestSubmitWorkflowRetriesWhenNotCreated(t *testing.T) {
+	server, posts := newSubmitTestServer(t, 2, false, 0)
+	defer server.Close()
+
+	client := newSubmitTestClient(server.URL, 3)
+	workflow, err := SubmitWorkflow(client, newSubmitTestConfig(server.URL))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if workflow.ID != 1 {
+		t.Errorf("expected workflow ID 1, got %v", workflow.ID)
+	}
+
+	if _, ok := SubmissionToken(workflow.Description); !ok {
+		t.Errorf("expected a submission token in the description, got %q", workflow.Description)
+	}
+
+	if n := posts(); n != 3 {
+		t.Errorf("expected 3 POST requests, got %d", n)
+	}
+}
+
+func TestSubmitWorkflowWithoutRetries(t *testing.T) {
+	server, posts := newSubmitTestServer(t, 1, false, 0)
+	defer server.Close()
+
+	client := newSubmitTestClient(server.URL, 0)
+	_, err := SubmitWorkflow(client, newSubmitTestConfig(server.URL))
+
+	if err == nil {
+		t.Fatal("expected error")
+	}
+
+	if n := posts(); n != 1 {
+		t.Errorf("expected 1 POST request, got %d", n)
+	}
+}
+
+func TestListWorkflowsByDescriptionOfSubmittedWorkflow(t *testing.T) {
+	server, _ := newSubmitTestServer(t, 0, false, 0)
+	defer server.Close()
+
+	client := newSubmitTestClient(server.URL, 0)
+	config := newSubmitTestConfig(server.URL)
+
+	if _, err := SubmitWorkflow(client, config); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, pattern := range []string{"sample", "*ample", "sample*", "/^sample$/"} {
+		filter := WorkflowFilter{Description: mustParsePattern(t, pattern)}
+		workflows, err := ListWorkflowsContext(context.Background(), client, WorkflowListOptions{Filter: filter, Limit: 1})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(workflows) != 1 || workflows[0].ID != 1 {
+			t.Errorf("%q: expected workflow 1, got %+v", pattern, workflows)
+		}
+	}
+}
+
+func TestFindWorkflowBySubmissionToken(t *testing.T) {
+	since := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	queries := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("$orderby")+" "+r.URL.Query().Get("$filter")+" "+r.URL.Query().Get("$top"))
+
+		json.NewEncoder(rw).Encode([]Workflow{
+			{ID: 2, CreatedDate: since, Description: withSubmissionToken("sample", "0123abcd")},
+		})
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+
+	workflow, ok, err := findWorkflowBySubmissionToken(context.Background(), client, "0123abcd", since)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ok || workflow.ID != 2 {
+		t.Errorf("expected workflow 2, got %+v (ok = %v)", workflow, ok)
+	}
+
+	_, ok, err = findWorkflowBySubmissionToken(context.Background(), client, "4567ef", since)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok {
+		t.Error("expected no workflow")
+	}
+
+	expected := "CreatedDate desc CreatedDate ge 2024-01-01T11:45:00Z and " +
+		"contains(Description,'[msgenctl-submission:0123abcd]') 1"
+
+	if len(queries) == 0 || queries[0] != expected {
+		t.Errorf("expected query %q, got %q", expected, queries)
+	}
+}
@@ -0,0 +1,506 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+// SummaryPeriod is a calendar period to group workflows by their creation
+// date, in UTC.
+type SummaryPeriod string
+
+const (
+	SummaryPeriodDay   = "day"
+	SummaryPeriodWeek  = "week"
+	SummaryPeriodMonth = "month"
+)
+
+func ParseSummaryPeriod(s string) (SummaryPeriod, error) {
+	switch s {
+	case "day":
+		return SummaryPeriodDay, nil
+	case "week":
+		return SummaryPeriodWeek, nil
+	case "month":
+		return SummaryPeriodMonth, nil
+	default:
+		return "", fmt.Errorf("invalid period: %q", s)
+	}
+}
+
+// key returns the period that contains the time, e.g., `2024-01-02` for a
+// day, `2024-W01` for an ISO week, or `2024-01` for a month.
+func (p SummaryPeriod) key(t time.Time) string {
+	t = t.UTC()
+
+	switch p {
+	case SummaryPeriodDay:
+		return t.Format(time.DateOnly)
+	case SummaryPeriodWeek:
+		year, week := t.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case SummaryPeriodMonth:
+		return t.Format("2006-01")
+	default:
+		return ""
+	}
+}
+
+// descriptionGroupName is the `--group-by` value to group by description
+// prefix.
+const descriptionGroupName = "description"
+
+// DefaultPrefixDelimiter separates the prefix of a description, e.g.,
+// `PROJ1` in `PROJ1-sample1`.
+const DefaultPrefixDelimiter = "-"
+
+// DescriptionPrefix returns the part of the description before the first
+// delimiter, without the submission token, e.g., `PROJ1` for
+// `PROJ1-sample1 [msgenctl-submission:<token>]`. A description without the
+// delimiter is its own prefix.
+func DescriptionPrefix(description string, delimiter string) string {
+	description = WithoutSubmissionToken(description)
+
+	if len(delimiter) == 0 {
+		return description
+	}
+
+	prefix, _, _ := strings.Cut(description, delimiter)
+
+	return strings.TrimSpace(prefix)
+}
+
+// SummaryOptions selects how workflows are grouped in a summary. Without a
+// period or description prefix, all workflows are in a single group.
+type SummaryOptions struct {
+	Period SummaryPeriod
+
+	// ByDescriptionPrefix groups workflows by the prefix of their
+	// description (see `DescriptionPrefix`).
+	ByDescriptionPrefix bool
+	PrefixDelimiter     string
+}
+
+// SummaryOptionsFromFlags builds summary options from the flags `--group-by`
+// and `--prefix-delimiter`.
+func SummaryOptionsFromFlags(flags *pflag.FlagSet) (SummaryOptions, error) {
+	options := SummaryOptions{}
+
+	groups, err := flags.GetStringSlice("group-by")
+
+	if err != nil {
+		return options, err
+	}
+
+	for _, group := range groups {
+		if group == descriptionGroupName {
+			options.ByDescriptionPrefix = true
+			continue
+		}
+
+		period, err := ParseSummaryPeriod(group)
+
+		if err != nil {
+			return options, fmt.Errorf("invalid group: %q", group)
+		}
+
+		if len(options.Period) > 0 {
+			return options, errors.New("at most one of day, week, or month can be grouped by")
+		}
+
+		options.Period = period
+	}
+
+	options.PrefixDelimiter, err = flags.GetString("prefix-delimiter")
+
+	if err != nil {
+		return options, err
+	}
+
+	return options, nil
+}
+
+// Summary aggregates workflows, e.g., for weekly reports.
+type Summary struct {
+	// GroupBy are the groupings, e.g., `week` and `description`.
+	GroupBy []string       `json:"groupBy"`
+	Groups  []SummaryGroup `json:"groups"`
+}
+
+// SummaryGroup aggregates the workflows of a period and description prefix,
+// if grouped by them.
+type SummaryGroup struct {
+	Period            string `json:"period,omitempty"`
+	DescriptionPrefix string `json:"descriptionPrefix,omitempty"`
+
+	Workflows int `json:"workflows"`
+
+	// Statuses counts the workflows by status name.
+	Statuses map[string]int `json:"statuses"`
+
+	BasesProcessed uint64           `json:"basesProcessed"`
+	Processes      []ProcessSummary `json:"processes"`
+
+	// Duration summarizes the wall clock time of completed workflows, i.e.,
+	// those with an end date.
+	Duration DurationSummary `json:"duration"`
+
+	// Failures counts the failed workflows by failure code.
+	Failures []FailureSummary `json:"failures"`
+}
+
+// ProcessSummary aggregates the workflows of a process.
+type ProcessSummary struct {
+	Process        string `json:"process"`
+	Workflows      int    `json:"workflows"`
+	BasesProcessed uint64 `json:"basesProcessed"`
+}
+
+// DurationSummary is the distribution of workflow durations. Percentiles use
+// the nearest-rank method.
+type DurationSummary struct {
+	Count int
+	P50   time.Duration
+	P90   time.Duration
+	Max   time.Duration
+}
+
+// MarshalJSON writes the durations in seconds.
+func (s DurationSummary) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Count      int     `json:"count"`
+		P50Seconds float64 `json:"p50Seconds"`
+		P90Seconds float64 `json:"p90Seconds"`
+		MaxSeconds float64 `json:"maxSeconds"`
+	}{s.Count, s.P50.Seconds(), s.P90.Seconds(), s.Max.Seconds()})
+}
+
+// FailureSummary counts the failed workflows with a failure code.
+type FailureSummary struct {
+	FailureCode int `json:"failureCode"`
+	Count       int `json:"count"`
+
+	// Message is the message of the most recent failure.
+	Message string `json:"message,omitempty"`
+}
+
+// Summarize aggregates the workflows in groups, ordered by period and then
+// description prefix.
+func Summarize(workflows []Workflow, options SummaryOptions) Summary {
+	summary := Summary{GroupBy: []string{}, Groups: []SummaryGroup{}}
+
+	if len(options.Period) > 0 {
+		summary.GroupBy = append(summary.GroupBy, string(options.Period))
+	}
+
+	if options.ByDescriptionPrefix {
+		summary.GroupBy = append(summary.GroupBy, descriptionGroupName)
+	}
+
+	type groupKey struct {
+		period string
+		prefix string
+	}
+
+	grouped := map[groupKey][]Workflow{}
+
+	for _, workflow := range workflows {
+		key := groupKey{period: options.Period.key(workflow.CreatedDate)}
+
+		if options.ByDescriptionPrefix {
+			key.prefix = DescriptionPrefix(workflow.Description, options.PrefixDelimiter)
+		}
+
+		grouped[key] = append(grouped[key], workflow)
+	}
+
+	keys := make([]groupKey, 0, len(grouped))
+
+	for key := range grouped {
+		keys = append(keys, key)
+	}
+
+	slices.SortFunc(keys, func(a groupKey, b groupKey) int {
+		return cmp.Or(cmp.Compare(a.period, b.period), cmp.Compare(a.prefix, b.prefix))
+	})
+
+	for _, key := range keys {
+		group := summarizeGroup(grouped[key])
+		group.Period = key.period
+		group.DescriptionPrefix = key.prefix
+		summary.Groups = append(summary.Groups, group)
+	}
+
+	return summary
+}
+
+func summarizeGroup(workflows []Workflow) SummaryGroup {
+	group := SummaryGroup{
+		Workflows: len(workflows),
+		Statuses:  map[string]int{},
+		Processes: []ProcessSummary{},
+		Failures:  []FailureSummary{},
+	}
+
+	processes := map[string]*ProcessSummary{}
+	failures := map[int]*FailureSummary{}
+	durations := []time.Duration{}
+
+	// Workflows are in creation order, so the last message of a failure code
+	// is the most recent.
+	for _, workflow := range workflows {
+		group.Statuses[workflow.Status.String()]++
+		group.BasesProcessed += workflow.BasesProcessed
+
+		process, ok := processes[workflow.Process]
+
+		if !ok {
+			process = &ProcessSummary{Process: workflow.Process}
+			processes[workflow.Process] = process
+		}
+
+		process.Workflows++
+		process.BasesProcessed += workflow.BasesProcessed
+
+		if workflow.EndDate != nil {
+			durations = append(durations, workflow.Duration())
+		}
+
+		if workflow.Status == StatusFailed {
+			failure, ok := failures[workflow.FailureCode]
+
+			if !ok {
+				failure = &FailureSummary{FailureCode: workflow.FailureCode}
+				failures[workflow.FailureCode] = failure
+			}
+
+			failure.Count++
+			failure.Message = workflow.Message
+		}
+	}
+
+	for _, process := range processes {
+		group.Processes = append(group.Processes, *process)
+	}
+
+	slices.SortFunc(group.Processes, func(a ProcessSummary, b ProcessSummary) int {
+		return cmp.Compare(a.Process, b.Process)
+	})
+
+	for _, failure := range failures {
+		group.Failures = append(group.Failures, *failure)
+	}
+
+	// The most common failures first.
+	slices.SortFunc(group.Failures, func(a FailureSummary, b FailureSummary) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.FailureCode, b.FailureCode))
+	})
+
+	group.Duration = summarizeDurations(durations)
+
+	return group
+}
+
+func summarizeDurations(durations []time.Duration) DurationSummary {
+	if len(durations) == 0 {
+		return DurationSummary{}
+	}
+
+	slices.Sort(durations)
+
+	percentile := func(p float64) time.Duration {
+		rank := int(math.Ceil(p * float64(len(durations))))
+		return durations[max(rank, 1)-1]
+	}
+
+	return DurationSummary{
+		Count: len(durations),
+		P50:   percentile(0.5),
+		P90:   percentile(0.9),
+		Max:   durations[len(durations)-1],
+	}
+}
+
+// WriteSummary writes the summary as tables or JSON.
+//
+// The table format has a table of workflow counts, bases, and durations per
+// group, followed by tables of bases per process and failures per failure
+// code, if any.
+func WriteSummary(w io.Writer, format OutputFormat, summary Summary) error {
+	switch format {
+	case OutputFormatTable:
+		return writeSummaryTables(w, summary)
+	case OutputFormatJSON:
+		return writeJSON(w, summary)
+	default:
+		return fmt.Errorf("invalid summary output format: %q", format)
+	}
+}
+
+func writeSummaryTables(w io.Writer, summary Summary) error {
+	byPeriod := slices.ContainsFunc(summary.GroupBy, func(group string) bool {
+		return group != descriptionGroupName
+	})
+	byPrefix := slices.Contains(summary.GroupBy, descriptionGroupName)
+
+	groupColumns := func(group *SummaryGroup) []string {
+		columns := []string{}
+
+		if byPeriod {
+			columns = append(columns, group.Period)
+		}
+
+		if byPrefix {
+			columns = append(columns, valueOrDash(group.DescriptionPrefix))
+		}
+
+		return columns
+	}
+
+	groupHeaders := groupColumns(&SummaryGroup{Period: "PERIOD", DescriptionPrefix: "PREFIX"})
+
+	statuses := summaryStatusNames(summary)
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	headers := append(slices.Clone(groupHeaders), "WORKFLOWS")
+
+	for _, status := range statuses {
+		headers = append(headers, strings.ToUpper(status))
+	}
+
+	headers = append(headers, "GBASES", "P50", "P90", "MAX")
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+
+	for _, group := range summary.Groups {
+		row := append(groupColumns(&group), fmt.Sprint(group.Workflows))
+
+		for _, status := range statuses {
+			row = append(row, fmt.Sprint(group.Statuses[status]))
+		}
+
+		row = append(
+			row,
+			formatGigabases(group.BasesProcessed),
+			formatSummaryDuration(group.Duration, group.Duration.P50),
+			formatSummaryDuration(group.Duration, group.Duration.P90),
+			formatSummaryDuration(group.Duration, group.Duration.Max),
+		)
+
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	hasFailures := slices.ContainsFunc(summary.Groups, func(group SummaryGroup) bool {
+		return len(group.Failures) > 0
+	})
+
+	if len(summary.Groups) > 0 {
+		fmt.Fprintln(w)
+
+		fmt.Fprintln(tw, strings.Join(append(slices.Clone(groupHeaders), "PROCESS", "WORKFLOWS", "GBASES"), "\t"))
+
+		for _, group := range summary.Groups {
+			for _, process := range group.Processes {
+				row := append(
+					groupColumns(&group),
+					valueOrDash(process.Process),
+					fmt.Sprint(process.Workflows),
+					formatGigabases(process.BasesProcessed),
+				)
+
+				fmt.Fprintln(tw, strings.Join(row, "\t"))
+			}
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if hasFailures {
+		fmt.Fprintln(w)
+
+		fmt.Fprintln(tw, strings.Join(append(slices.Clone(groupHeaders), "FAILURE CODE", "COUNT", "LAST MESSAGE"), "\t"))
+
+		for _, group := range summary.Groups {
+			for _, failure := range group.Failures {
+				row := append(
+					groupColumns(&group),
+					fmt.Sprint(failure.FailureCode),
+					fmt.Sprint(failure.Count),
+					failure.Message,
+				)
+
+				fmt.Fprintln(tw, strings.Join(row, "\t"))
+			}
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// summaryStatusNames returns the names of the statuses in the summary, known
+// statuses in lifecycle order first.
+func summaryStatusNames(summary Summary) []string {
+	names := []string{}
+	unknown := []string{}
+
+	for _, group := range summary.Groups {
+		for name := range group.Statuses {
+			if !slices.Contains(StatusNames, name) && !slices.Contains(unknown, name) {
+				unknown = append(unknown, name)
+			}
+		}
+	}
+
+	for _, name := range StatusNames {
+		for _, group := range summary.Groups {
+			if group.Statuses[name] > 0 {
+				names = append(names, name)
+				break
+			}
+		}
+	}
+
+	slices.Sort(unknown)
+
+	return append(names, unknown...)
+}
+
+func formatGigabases(bases uint64) string {
+	return fmt.Sprintf("%.2f", gigabases(bases))
+}
+
+func formatSummaryDuration(summary DurationSummary, d time.Duration) string {
+	if summary.Count == 0 {
+		return "-"
+	}
+
+	return formatDuration(d)
+}
+
+func valueOrDash(s string) string {
+	if len(s) == 0 {
+		return "-"
+	}
+
+	return s
+}
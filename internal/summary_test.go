@@ -0,0 +1,265 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func newSummaryTestWorkflows() []Workflow {
+	newWorkflow := func(
+		id WorkflowID,
+		createdDate time.Time,
+		duration time.Duration,
+		status Status,
+		description string,
+		bases uint64,
+	) Workflow {
+		workflow := Workflow{
+			ID:             id,
+			Status:         status,
+			CreatedDate:    createdDate,
+			Description:    description,
+			Process:        "snapgatk-20230626_1",
+			BasesProcessed: bases,
+		}
+
+		if duration > 0 {
+			endDate := createdDate.Add(duration)
+			workflow.EndDate = &endDate
+		}
+
+		if status == StatusFailed {
+			workflow.FailureCode = 301
+			workflow.Message = "invalid input " + description
+		}
+
+		return workflow
+	}
+
+	monday := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	nextMonday := monday.AddDate(0, 0, 7)
+
+	workflows := []Workflow{
+		newWorkflow(1, monday, time.Hour, StatusSuccess, "PROJ1-a [msgenctl-submission:0123abcd]", 1_000_000_000),
+		newWorkflow(2, monday.Add(time.Hour), 2*time.Hour, StatusSuccess, "PROJ2-b", 2_000_000_000),
+		newWorkflow(3, monday.Add(2*time.Hour), 3*time.Hour, StatusSuccess, "PROJ1-c", 3_000_000_000),
+		newWorkflow(4, nextMonday, 10*time.Minute, StatusFailed, "PROJ1-d", 0),
+		newWorkflow(5, nextMonday.Add(time.Hour), 0, StatusWorking, "PROJ1-e", 0),
+	}
+
+	workflows[3].Process = "other"
+
+	return workflows
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize(newSummaryTestWorkflows(), SummaryOptions{})
+
+	if len(summary.Groups) != 1 {
+		t.Fatalf("expected 1 group, got %d", len(summary.Groups))
+	}
+
+	expected := SummaryGroup{
+		Workflows:      5,
+		Statuses:       map[string]int{"success": 3, "failed": 1, "working": 1},
+		BasesProcessed: 6_000_000_000,
+		Processes: []ProcessSummary{
+			{Process: "other", Workflows: 1, BasesProcessed: 0},
+			{Process: "snapgatk-20230626_1", Workflows: 4, BasesProcessed: 6_000_000_000},
+		},
+		Duration: DurationSummary{
+			Count: 4,
+			P50:   time.Hour,
+			P90:   3 * time.Hour,
+			Max:   3 * time.Hour,
+		},
+		Failures: []FailureSummary{
+			{FailureCode: 301, Count: 1, Message: "invalid input PROJ1-d"},
+		},
+	}
+
+	if diff := cmp.Diff(summary.Groups[0], expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestSummarizeWithGroups(t *testing.T) {
+	test := func(t testing.TB, options SummaryOptions, expected [][2]string) {
+		t.Helper()
+
+		summary := Summarize(newSummaryTestWorkflows(), options)
+		actual := [][2]string{}
+
+		for _, group := range summary.Groups {
+			actual = append(actual, [2]string{group.Period, group.DescriptionPrefix})
+		}
+
+		if diff := cmp.Diff(actual, expected); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+	}
+
+	test(t, SummaryOptions{Period: SummaryPeriodDay}, [][2]string{{"2024-01-01", ""}, {"2024-01-08", ""}})
+	test(t, SummaryOptions{Period: SummaryPeriodWeek}, [][2]string{{"2024-W01", ""}, {"2024-W02", ""}})
+	test(t, SummaryOptions{Period: SummaryPeriodMonth}, [][2]string{{"2024-01", ""}})
+	test(
+		t,
+		SummaryOptions{ByDescriptionPrefix: true, PrefixDelimiter: "-"},
+		[][2]string{{"", "PROJ1"}, {"", "PROJ2"}},
+	)
+	test(
+		t,
+		SummaryOptions{Period: SummaryPeriodWeek, ByDescriptionPrefix: true, PrefixDelimiter: "-"},
+		[][2]string{{"2024-W01", "PROJ1"}, {"2024-W01", "PROJ2"}, {"2024-W02", "PROJ1"}},
+	)
+}
+
+func TestDescriptionPrefix(t *testing.T) {
+	test := func(t testing.TB, description string, delimiter string, expected string) {
+		t.Helper()
+
+		if actual := DescriptionPrefix(description, delimiter); actual != expected {
+			t.Errorf("%q: expected %q, got %q", description, expected, actual)
+		}
+	}
+
+	test(t, "PROJ1-sample1", "-", "PROJ1")
+	test(t, "PROJ1_sample1", "_", "PROJ1")
+	test(t, "sample1 [msgenctl-submission:0123abcd]", "-", "sample1")
+	test(t, "PROJ1-sample1", "", "PROJ1-sample1")
+	test(t, "", "-", "")
+}
+
+func TestSummarizeDurations(t *testing.T) {
+	durations := []time.Duration{}
+
+	for i := 10; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Minute)
+	}
+
+	expected := DurationSummary{Count: 10, P50: 5 * time.Minute, P90: 9 * time.Minute, Max: 10 * time.Minute}
+
+	if diff := cmp.Diff(summarizeDurations(durations), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if diff := cmp.Diff(summarizeDurations(nil), DurationSummary{}); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestWriteSummaryWithTable(t *testing.T) {
+	summary := Summarize(newSummaryTestWorkflows(), SummaryOptions{Period: SummaryPeriodWeek})
+
+	var buf bytes.Buffer
+
+	if err := WriteSummary(&buf, OutputFormatTable, summary); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"PERIOD    WORKFLOWS  WORKING  SUCCESS  FAILED  GBASES  P50  P90  MAX\n" +
+		"2024-W01  3          0        3        0       6.00    2h   3h   3h\n" +
+		"2024-W02  2          1        0        1       0.00    10m  10m  10m\n" +
+		"\n" +
+		"PERIOD    PROCESS              WORKFLOWS  GBASES\n" +
+		"2024-W01  snapgatk-20230626_1  3          6.00\n" +
+		"2024-W02  other                1          0.00\n" +
+		"2024-W02  snapgatk-20230626_1  1          0.00\n" +
+		"\n" +
+		"PERIOD    FAILURE CODE  COUNT  LAST MESSAGE\n" +
+		"2024-W02  301           1      invalid input PROJ1-d\n"
+
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestWriteSummaryWithJSON(t *testing.T) {
+	summary := Summarize(newSummaryTestWorkflows(), SummaryOptions{ByDescriptionPrefix: true, PrefixDelimiter: "-"})
+
+	var buf bytes.Buffer
+
+	if err := WriteSummary(&buf, OutputFormatJSON, summary); err != nil {
+		t.Fatal(err)
+	}
+
+	var actual struct {
+		GroupBy []string `json:"groupBy"`
+		Groups  []struct {
+			DescriptionPrefix string         `json:"descriptionPrefix"`
+			Statuses          map[string]int `json:"statuses"`
+			Duration          struct {
+				Count      int     `json:"count"`
+				MaxSeconds float64 `json:"maxSeconds"`
+			} `json:"duration"`
+		} `json:"groups"`
+	}
+
+	if err := json.Unmarshal(buf.Bytes(), &actual); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(actual.GroupBy, []string{"description"}); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if len(actual.Groups) != 2 || actual.Groups[0].DescriptionPrefix != "PROJ1" {
+		t.Fatalf("unexpected groups: %+v", actual.Groups)
+	}
+
+	if actual.Groups[0].Duration.Count != 3 || actual.Groups[0].Duration.MaxSeconds != 10800 {
+		t.Errorf("unexpected duration: %+v", actual.Groups[0].Duration)
+	}
+
+	if err := WriteSummary(&buf, OutputFormatCSV, summary); err == nil {
+		t.Error("expected error for an unsupported output format")
+	}
+}
+
+func TestSummaryOptionsFromFlags(t *testing.T) {
+	newFlags := func() *pflag.FlagSet {
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		flags.StringSlice("group-by", nil, "")
+		flags.String("prefix-delimiter", DefaultPrefixDelimiter, "")
+		return flags
+	}
+
+	flags := newFlags()
+
+	if err := flags.Parse([]string{"--group-by", "month,description"}); err != nil {
+		t.Fatal(err)
+	}
+
+	options, err := SummaryOptionsFromFlags(flags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := SummaryOptions{Period: SummaryPeriodMonth, ByDescriptionPrefix: true, PrefixDelimiter: "-"}
+
+	if diff := cmp.Diff(options, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	for _, args := range [][]string{
+		{"--group-by", "year"},
+		{"--group-by", "day,week"},
+	} {
+		flags := newFlags()
+
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := SummaryOptionsFromFlags(flags); err == nil {
+			t.Errorf("%v: expected error", args)
+		}
+	}
+}
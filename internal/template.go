@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"golang.org/x/term"
+)
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+)
+
+// NewWorkflowTemplate parses a Go template that is executed with a `Workflow`,
+// e.g., `{{.ID}}\t{{.Status}}`.
+//
+// Besides the builtin functions, templates can use
+//
+//   - `duration`, which formats a `time.Duration` compactly, e.g., `1d2h`,
+//     `2h5m`, or `12s`, e.g., `{{duration .Duration}}`.
+//   - `ago`, which formats a time relative to now, e.g., `3h ago`, e.g.,
+//     `{{ago .CreatedDate}}`.
+//   - `colorStatus`, which colors a status by outcome if stdout is a terminal
+//     and `NO_COLOR` is not set, e.g., `{{colorStatus .Status}}`.
+//   - `json`, which formats a value as JSON.
+func NewWorkflowTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("output").Funcs(templateFuncs(time.Now)).Parse(text)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+
+	return tmpl, nil
+}
+
+func templateFuncs(now func() time.Time) template.FuncMap {
+	return template.FuncMap{
+		"duration": formatDuration,
+		"ago": func(v any) (string, error) {
+			var t time.Time
+
+			switch v := v.(type) {
+			case time.Time:
+				t = v
+			case *time.Time:
+				if v == nil {
+					return "-", nil
+				}
+
+				t = *v
+			default:
+				return "", fmt.Errorf("ago: expected a time, got %T", v)
+			}
+
+			d := now().Sub(t)
+
+			if d < 0 {
+				return "in " + formatDuration(-d), nil
+			}
+
+			return formatDuration(d) + " ago", nil
+		},
+		"colorStatus": func(status Status) string {
+			return colorStatus(status, isColorEnabled())
+		},
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}
+}
+
+// formatDuration formats the duration with its two most significant units,
+// e.g., `1d2h`, `2h5m`, `5m3s`, or `12s`, rounded down.
+func formatDuration(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDuration(-d)
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	var b strings.Builder
+
+	for i, unit := range units {
+		if d < unit.size && i < len(units)-1 {
+			continue
+		}
+
+		fmt.Fprintf(&b, "%d%s", d/unit.size, unit.suffix)
+
+		if i < len(units)-1 {
+			if rest := (d % unit.size) / units[i+1].size; rest > 0 {
+				fmt.Fprintf(&b, "%d%s", rest, units[i+1].suffix)
+			}
+		}
+
+		break
+	}
+
+	return b.String()
+}
+
+// isColorEnabled returns whether output may be colored, i.e., stdout is a
+// terminal and `NO_COLOR` is not set. Colors are not written to pipes or
+// files.
+func isColorEnabled() bool {
+	return len(os.Getenv("NO_COLOR")) == 0 && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// colorStatus returns the status name, colored by outcome with ANSI escape
+// codes if enabled: green for success, red for failed, yellow for
+// cancelling and cancelled, and blue otherwise.
+func colorStatus(status Status, enabled bool) string {
+	if !enabled {
+		return status.String()
+	}
+
+	color := ansiBlue
+
+	switch status {
+	case StatusSuccess:
+		color = ansiGreen
+	case StatusFailed:
+		color = ansiRed
+	case StatusCancelling, StatusCancelled:
+		color = ansiYellow
+	}
+
+	return color + status.String() + ansiReset
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFormatDuration(t *testing.T) {
+	test := func(t testing.TB, d time.Duration, expected string) {
+		t.Helper()
+
+		if actual := formatDuration(d); actual != expected {
+			t.Errorf("%s: expected %q, got %q", d, expected, actual)
+		}
+	}
+
+	test(t, 0, "0s")
+	test(t, 12*time.Second+500*time.Millisecond, "12s")
+	test(t, 5*time.Minute+3*time.Second, "5m3s")
+	test(t, 2*time.Hour+5*time.Minute+30*time.Second, "2h5m")
+	test(t, 2*time.Hour, "2h")
+	test(t, 26*time.Hour, "1d2h")
+	test(t, -90*time.Second, "-1m30s")
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	now := time.Date(2024, 1, 1, 3, 0, 0, 0, time.UTC)
+	funcs := templateFuncs(func() time.Time { return now })
+
+	test := func(t testing.TB, text string, data any, expected string) {
+		t.Helper()
+
+		tmpl, err := template.New("test").Funcs(funcs).Parse(text)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := tmpl.Execute(&buf, data); err != nil {
+			t.Fatalf("%s: %v", text, err)
+		}
+
+		if diff := cmp.Diff(buf.String(), expected); diff != "" {
+			t.Errorf("%s: mismatch (-actual, +expected):\n%s", text, diff)
+		}
+	}
+
+	workflows := newTestWorkflows()
+
+	test(t, "{{ago .CreatedDate}}", workflows[0], "3h ago")
+	test(t, "{{ago .EndDate}}", workflows[0], "1h ago")
+	test(t, "{{ago .EndDate}}", Workflow{}, "-")
+	test(t, "{{ago .}}", now.Add(time.Minute), "in 1m")
+	test(t, "{{duration .}}", 90*time.Minute, "1h30m")
+	test(t, "{{json .}}", map[string]int{"id": 1}, `{"id":1}`)
+}
+
+func TestColorStatus(t *testing.T) {
+	if actual := colorStatus(StatusSuccess, false); actual != "success" {
+		t.Errorf("expected no color, got %q", actual)
+	}
+
+	if actual, expected := colorStatus(StatusFailed, true), ansiRed+"failed"+ansiReset; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	if actual, expected := colorStatus(Status(70000), false), "unknown(70000)"; actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func TestWorkflowWriter(t *testing.T) {
+	test := func(t testing.TB, options OutputOptions, expected string) {
+		t.Helper()
+
+		writer, err := NewWorkflowWriter(options)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := writer.WriteWorkflows(&buf, newTestWorkflows()); err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(buf.String(), expected); diff != "" {
+			t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+		}
+	}
+
+	test(t, OutputOptions{Template: "{{.ID}}\t{{.Status}}"}, "1\tsuccess\n2\tfailed\n")
+	test(t, OutputOptions{Template: "{{.ID}} {{duration .Duration}}"}, "1 2h\n2 10m\n")
+	test(t, OutputOptions{Template: "{{.Description}}"}, "sample, one\n")
+
+	// stdout is not a terminal in tests.
+	test(t, OutputOptions{Template: "{{colorStatus .Status}}"}, "success\nfailed\n")
+	test(t, OutputOptions{JSONPath: "{.items[*].id}"}, "1 2\n")
+	test(t, OutputOptions{JSONPath: "{.items[0].description}/{.items[0].submissionToken}"}, "sample, one/0123456789abcdef01234567\n")
+	test(t, OutputOptions{JSONPath: `{range .items[*]}{.id}{"\t"}{.failureCode}{"\n"}{end}`}, "1\t0\n2\t301\n")
+
+	writer, err := NewWorkflowWriter(OutputOptions{JSONPath: "{.status}"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := writer.WriteWorkflow(&buf, newTestWorkflows()[1]); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(buf.String(), "failed\n"); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestNewWorkflowWriterWithInvalidOptions(t *testing.T) {
+	for _, options := range []OutputOptions{
+		{Template: "{{.ID}}", JSONPath: "{.id}"},
+		{Template: "{{.ID"},
+		{JSONPath: "{.id"},
+	} {
+		if _, err := NewWorkflowWriter(options); err == nil {
+			t.Errorf("%+v: expected error", options)
+		}
+	}
+}
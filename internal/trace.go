@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/stjudecloud/msgenctl"
+
+// Trace exporter names, as in `OTEL_TRACES_EXPORTER`.
+const (
+	traceExporterOTLP    = "otlp"
+	traceExporterConsole = "console"
+	traceExporterNone    = "none"
+)
+
+// TraceOptions configures tracing. The exporters are otherwise configured by
+// the standard OpenTelemetry environment variables (see `SetUpTracing`).
+type TraceOptions struct {
+	// File is the path of the file to append spans to as JSON, one per line.
+	// If empty, spans are not written to a file.
+	File string
+}
+
+// SetUpTracing sets the global tracer provider and the W3C trace context and
+// baggage propagators. It returns a function that flushes pending spans and
+// must be called before exiting.
+//
+// Spans are exported to each of
+//
+//   - an OTLP/HTTP endpoint if `OTEL_TRACES_EXPORTER` is `otlp` or, when it
+//     is unset, `OTEL_EXPORTER_OTLP_ENDPOINT` or
+//     `OTEL_EXPORTER_OTLP_TRACES_ENDPOINT` is set. The exporter reads the
+//     other `OTEL_EXPORTER_OTLP_*` variables, e.g., for headers.
+//   - stderr if `OTEL_TRACES_EXPORTER` is `console`.
+//   - the file in the options, if any.
+//
+// Tracing is disabled when there is no exporter or `OTEL_SDK_DISABLED` is
+// `true`. The resource is read from `OTEL_SERVICE_NAME` and
+// `OTEL_RESOURCE_ATTRIBUTES`, with a default service name of `msgenctl`.
+func SetUpTracing(ctx context.Context, options TraceOptions) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	noop := func(context.Context) error { return nil }
+
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return noop, nil
+	}
+
+	exporters, closer, err := newSpanExporters(ctx, os.LookupEnv, options)
+
+	if err != nil {
+		return noop, err
+	}
+
+	if len(exporters) == 0 {
+		return noop, nil
+	}
+
+	res, err := resource.New(
+		ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", "msgenctl"),
+			attribute.String("service.version", Version),
+		),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+
+	if err != nil {
+		closer.Close()
+		return noop, err
+	}
+
+	providerOptions := []sdktrace.TracerProviderOption{sdktrace.WithResource(res)}
+
+	for _, exporter := range exporters {
+		providerOptions = append(providerOptions, sdktrace.WithBatcher(exporter))
+	}
+
+	provider := sdktrace.NewTracerProvider(providerOptions...)
+	otel.SetTracerProvider(provider)
+
+	shutdown := func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closer.Close())
+	}
+
+	return shutdown, nil
+}
+
+// newSpanExporters returns the span exporters selected by the environment
+// and options (see `SetUpTracing`) and a closer for the trace file, if any.
+func newSpanExporters(
+	ctx context.Context,
+	lookupEnv func(string) (string, bool),
+	options TraceOptions,
+) ([]sdktrace.SpanExporter, io.Closer, error) {
+	var closer io.Closer = io.NopCloser(nil)
+
+	exporters := []sdktrace.SpanExporter{}
+
+	name, ok := lookupEnv("OTEL_TRACES_EXPORTER")
+
+	if !ok {
+		name = traceExporterNone
+
+		for _, key := range []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"} {
+			if endpoint, ok := lookupEnv(key); ok && len(endpoint) > 0 {
+				name = traceExporterOTLP
+			}
+		}
+	}
+
+	switch name {
+	case traceExporterOTLP:
+		exporter, err := otlptracehttp.New(ctx)
+
+		if err != nil {
+			return nil, closer, fmt.Errorf("could not create OTLP trace exporter: %w", err)
+		}
+
+		exporters = append(exporters, exporter)
+	case traceExporterConsole:
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(os.Stderr))
+
+		if err != nil {
+			return nil, closer, err
+		}
+
+		exporters = append(exporters, exporter)
+	case traceExporterNone, "":
+	default:
+		return nil, closer, fmt.Errorf("invalid OTEL_TRACES_EXPORTER: %q", name)
+	}
+
+	if len(options.File) > 0 {
+		f, err := os.OpenFile(options.File, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+
+		if err != nil {
+			return nil, closer, fmt.Errorf("could not open trace file: %w", err)
+		}
+
+		closer = f
+
+		exporter, err := stdouttrace.New(stdouttrace.WithWriter(f))
+
+		if err != nil {
+			f.Close()
+			return nil, closer, err
+		}
+
+		exporters = append(exporters, exporter)
+	}
+
+	return exporters, closer, nil
+}
+
+// StartSpan starts a span using the global tracer provider.
+func StartSpan(
+	ctx context.Context,
+	name string,
+	attrs ...attribute.KeyValue,
+) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// EndSpan records the error, if any, on the span and ends it. Error messages
+// are redacted (see `Redact`).
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		message := Redact(err.Error())
+		span.RecordError(errors.New(message))
+		span.SetStatus(codes.Error, message)
+	}
+
+	span.End()
+}
@@ -0,0 +1,266 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// useTestTracerProvider sets a global tracer provider that records spans in
+// memory for the duration of the test.
+func useTestTracerProvider(t testing.TB) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+
+	return attribute.Value{}, false
+}
+
+func TestClientTracesRequests(t *testing.T) {
+	exporter := useTestTracerProvider(t)
+
+	var attempts atomic.Int32
+	var traceparent atomic.Value
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		traceparent.Store(r.Header.Get("traceparent"))
+
+		if attempts.Add(1) == 1 {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		rw.Write([]byte("[]"))
+	}))
+
+	defer server.Close()
+
+	options := DefaultClientOptions()
+	options.RetryWaitMin = time.Millisecond
+	options.RetryWaitMax = time.Millisecond
+
+	client := NewClientWithOptions(server.URL, "secret", options)
+
+	ctx, parent := StartSpan(context.Background(), "msgenctl status")
+	response, err := client.GetContext(ctx, "/api/workflows")
+	parent.End()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response.Body.Close()
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+
+	span := spans[0]
+
+	if span.Name != "GET /api/workflows" {
+		t.Errorf("expected span name %q, got %q", "GET /api/workflows", span.Name)
+	}
+
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("expected the request span to be a child of the parent span")
+	}
+
+	if value, _ := spanAttribute(span, "http.response.status_code"); value.AsInt64() != http.StatusOK {
+		t.Errorf("expected status code %d, got %v", http.StatusOK, value.Emit())
+	}
+
+	if value, _ := spanAttribute(span, "http.request.resend_count"); value.AsInt64() != 1 {
+		t.Errorf("expected resend count 1, got %v", value.Emit())
+	}
+
+	expectedTraceparent := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"
+
+	if actual := traceparent.Load(); actual != expectedTraceparent {
+		t.Errorf("expected traceparent %q, got %q", expectedTraceparent, actual)
+	}
+}
+
+func TestClientTracesFailedRequests(t *testing.T) {
+	exporter := useTestTracerProvider(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusNotFound)
+	}))
+
+	defer server.Close()
+
+	client := NewClient(server.URL, "secret")
+	_, err := client.GetContext(context.Background(), "/api/workflows/1")
+
+	var apiError *APIError
+
+	if !errors.As(err, &apiError) {
+		t.Fatalf("expected *APIError, got %v", err)
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+
+	if spans[0].Status.Code != codes.Error {
+		t.Errorf("expected error status, got %v", spans[0].Status.Code)
+	}
+
+	if value, _ := spanAttribute(spans[0], "http.response.status_code"); value.AsInt64() != http.StatusNotFound {
+		t.Errorf("expected status code %d, got %v", http.StatusNotFound, value.Emit())
+	}
+}
+
+func TestBlobServiceClientTracesSigning(t *testing.T) {
+	exporter := useTestTracerProvider(t)
+
+	blobServiceClient, err := NewBlobServiceClient("msgenctl", "bXNnZW5jdGw=")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = blobServiceClient.GenerateBlobSAS("test", "in.bam", sas.BlobPermissions{Read: true})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+
+	if len(spans) != 1 || spans[0].Name != "sign SAS" {
+		t.Fatalf("expected 1 %q span, got %v", "sign SAS", spans)
+	}
+
+	if value, _ := spanAttribute(spans[0], "azure.storage.blob"); value.AsString() != "in.bam" {
+		t.Errorf("expected blob %q, got %q", "in.bam", value.AsString())
+	}
+}
+
+func TestNewSpanExporters(t *testing.T) {
+	test := func(t testing.TB, env map[string]string, options TraceOptions, expected int) {
+		t.Helper()
+
+		lookupEnv := func(key string) (string, bool) {
+			value, ok := env[key]
+			return value, ok
+		}
+
+		exporters, closer, err := newSpanExporters(context.Background(), lookupEnv, options)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		defer closer.Close()
+
+		if len(exporters) != expected {
+			t.Errorf("env = %v: expected %d exporters, got %d", env, expected, len(exporters))
+		}
+	}
+
+	file := TraceOptions{File: filepath.Join(t.TempDir(), "traces.json")}
+
+	test(t, map[string]string{}, TraceOptions{}, 0)
+	test(t, map[string]string{"OTEL_TRACES_EXPORTER": "none"}, TraceOptions{}, 0)
+	test(t, map[string]string{"OTEL_TRACES_EXPORTER": "console"}, TraceOptions{}, 1)
+	test(t, map[string]string{"OTEL_TRACES_EXPORTER": "otlp"}, TraceOptions{}, 1)
+	test(t, map[string]string{"OTEL_EXPORTER_OTLP_ENDPOINT": "http://localhost:4318"}, TraceOptions{}, 1)
+	test(t, map[string]string{"OTEL_EXPORTER_OTLP_TRACES_ENDPOINT": "http://localhost:4318"}, file, 2)
+	test(t, map[string]string{"OTEL_TRACES_EXPORTER": "none"}, file, 1)
+
+	lookupEnv := func(key string) (string, bool) {
+		return "zipkin", key == "OTEL_TRACES_EXPORTER"
+	}
+
+	if _, _, err := newSpanExporters(context.Background(), lookupEnv, TraceOptions{}); err == nil {
+		t.Error("expected error for an unsupported exporter")
+	}
+}
+
+func TestNewSpanExportersWithFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traces.json")
+	noEnv := func(string) (string, bool) { return "", false }
+
+	exporters, closer, err := newSpanExporters(context.Background(), noEnv, TraceOptions{File: path})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporters[0]))
+	_, span := provider.Tracer(tracerName).Start(context.Background(), "msgenctl submit")
+	EndSpan(span, errors.New("invalid sig=c2lnbmF0dXJl"))
+
+	if err := provider.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := closer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var record struct {
+		Name   string
+		Status struct{ Description string }
+	}
+
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatalf("invalid JSON span: %v: %s", err, data)
+	}
+
+	if record.Name != "msgenctl submit" {
+		t.Errorf("expected span name %q, got %q", "msgenctl submit", record.Name)
+	}
+
+	if expected := "invalid sig=" + secretMask; record.Status.Description != expected {
+		t.Errorf("expected redacted status %q, got %q", expected, record.Status.Description)
+	}
+}
@@ -0,0 +1,181 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func writePEM(t testing.TB, blockType string, data []byte) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cert.pem")
+	block := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data})
+
+	if err := os.WriteFile(path, block, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// newClientCertificate returns the paths to a self-signed client certificate
+// and its private key.
+func newClientCertificate(t testing.TB) (*x509.Certificate, string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "msgenctl"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return certificate, writePEM(t, "CERTIFICATE", der), writePEM(t, "EC PRIVATE KEY", keyDER)
+}
+
+func getWithTransport(t testing.TB, options TransportOptions, url string) error {
+	t.Helper()
+
+	transport, err := NewTransport(options)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := http.Client{Transport: transport}
+	response, err := client.Get(url)
+
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+
+	return nil
+}
+
+func TestNewTransportWithCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	if err := getWithTransport(t, TransportOptions{}, server.URL); err == nil {
+		t.Error("expected failure without the CA bundle")
+	}
+
+	caBundle := writePEM(t, "CERTIFICATE", server.Certificate().Raw)
+
+	if err := getWithTransport(t, TransportOptions{CABundle: caBundle}, server.URL); err != nil {
+		t.Errorf("unexpected failure with the CA bundle: %v", err)
+	}
+
+	if err := getWithTransport(t, TransportOptions{InsecureSkipVerify: true}, server.URL); err != nil {
+		t.Errorf("unexpected failure with insecure-skip-verify: %v", err)
+	}
+}
+
+func TestNewTransportWithClientCertificate(t *testing.T) {
+	certificate, certPath, keyPath := newClientCertificate(t)
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(certificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: clientCAs}
+	server.StartTLS()
+	defer server.Close()
+
+	caBundle := writePEM(t, "CERTIFICATE", server.Certificate().Raw)
+
+	if err := getWithTransport(t, TransportOptions{CABundle: caBundle}, server.URL); err == nil {
+		t.Error("expected failure without a client certificate")
+	}
+
+	options := TransportOptions{CABundle: caBundle, ClientCert: certPath, ClientKey: keyPath}
+
+	if err := getWithTransport(t, options, server.URL); err != nil {
+		t.Errorf("unexpected failure with a client certificate: %v", err)
+	}
+}
+
+func TestNewTransportWithProxy(t *testing.T) {
+	var requests atomic.Int32
+
+	proxy := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+
+		if r.URL.Host != "msgenctl.example" {
+			t.Errorf("expected proxied host %q, got %q", "msgenctl.example", r.URL.Host)
+		}
+	}))
+
+	defer proxy.Close()
+
+	if err := getWithTransport(t, TransportOptions{ProxyURL: proxy.URL}, "http://msgenctl.example/"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := requests.Load(); n != 1 {
+		t.Errorf("expected 1 proxied request, got %d", n)
+	}
+}
+
+func TestNewTransportWithInvalidOptions(t *testing.T) {
+	test := func(t testing.TB, options TransportOptions) {
+		t.Helper()
+
+		if _, err := NewTransport(options); err == nil {
+			t.Errorf("expected failure: options = %+v", options)
+		}
+	}
+
+	_, certPath, _ := newClientCertificate(t)
+	notPEM := filepath.Join(t.TempDir(), "not.pem")
+
+	if err := os.WriteFile(notPEM, []byte("msgenctl"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	test(t, TransportOptions{ProxyURL: "proxy:3128"})
+	test(t, TransportOptions{CABundle: filepath.Join(t.TempDir(), "missing.pem")})
+	test(t, TransportOptions{CABundle: notPEM})
+	test(t, TransportOptions{ClientCert: certPath})
+}
@@ -0,0 +1,329 @@
+package internal
+
+import (
+	"cmp"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/pflag"
+)
+
+// Prices maps process names (e.g., `snapgatk-20230626_1`) to prices per
+// gigabase (10^9 bases processed). The `default` price applies to processes
+// without their own price. Prices are set in the configuration file, e.g.,
+//
+//	prices:
+//	  default: 0.29
+//	  snapgatk-20230626_1: 0.25
+type Prices map[string]float64
+
+// defaultPriceName is the `Prices` key of the price of other processes.
+const defaultPriceName = "default"
+
+// Price returns the price per gigabase of the process, if any.
+func (p Prices) Price(process string) (float64, bool) {
+	if price, ok := p[process]; ok {
+		return price, true
+	}
+
+	price, ok := p[defaultPriceName]
+
+	return price, ok
+}
+
+// UsageOptions selects how usage is attributed and priced.
+type UsageOptions struct {
+	// PrefixDelimiter ends the project in a workflow description (see
+	// `DescriptionPrefix`).
+	PrefixDelimiter string
+
+	Prices Prices
+}
+
+// UsageOptionsFromFlags builds usage options from the flags
+// `--prefix-delimiter` and `--price-per-gbase`, which overrides the default
+// price of the given prices.
+func UsageOptionsFromFlags(flags *pflag.FlagSet, prices Prices) (UsageOptions, error) {
+	options := UsageOptions{Prices: maps.Clone(prices)}
+
+	if options.Prices == nil {
+		options.Prices = Prices{}
+	}
+
+	prefixDelimiter, err := flags.GetString("prefix-delimiter")
+
+	if err != nil {
+		return options, err
+	}
+
+	options.PrefixDelimiter = prefixDelimiter
+
+	if flags.Changed("price-per-gbase") {
+		price, err := flags.GetFloat64("price-per-gbase")
+
+		if err != nil {
+			return options, err
+		}
+
+		options.Prices[defaultPriceName] = price
+	}
+
+	for process, price := range options.Prices {
+		if price < 0 {
+			return options, fmt.Errorf("invalid price for %q: %v: must be non-negative", process, price)
+		}
+	}
+
+	return options, nil
+}
+
+// UsageReport is the usage of completed workflows per month, process,
+// project, and status. Failed and cancelled workflows are reported apart from
+// successful ones, as they may still be billed for the bases they processed.
+type UsageReport struct {
+	Rows []UsageRow `json:"rows"`
+
+	// Totals are the usage per status over all rows.
+	Totals []UsageRow `json:"totals"`
+
+	// Incomplete counts the workflows that are excluded because they have not
+	// completed, e.g., queued or running workflows.
+	Incomplete int `json:"incomplete"`
+}
+
+// UsageRow is the usage of workflows with the same status in a month (of
+// their creation date, in UTC), process, and project (the prefix of their
+// description).
+//
+// The price and cost are unset if no price applies to the process. A total
+// has no cost if any of its rows has none.
+type UsageRow struct {
+	Month   string `json:"month,omitempty"`
+	Process string `json:"process,omitempty"`
+	Project string `json:"project,omitempty"`
+	Status  string `json:"status"`
+
+	Workflows      int      `json:"workflows"`
+	BasesProcessed uint64   `json:"basesProcessed"`
+	PricePerGbase  *float64 `json:"pricePerGbase"`
+	Cost           *float64 `json:"cost"`
+}
+
+// usageRowColumns are the CSV columns, in the same order as the fields of
+// `UsageRow`.
+var usageRowColumns = []string{
+	"month",
+	"process",
+	"project",
+	"status",
+	"workflows",
+	"basesProcessed",
+	"pricePerGbase",
+	"cost",
+}
+
+func (r *UsageRow) values() []string {
+	return []string{
+		r.Month,
+		r.Process,
+		r.Project,
+		r.Status,
+		strconv.Itoa(r.Workflows),
+		strconv.FormatUint(r.BasesProcessed, 10),
+		formatOptionalFloat(r.PricePerGbase, -1),
+		formatOptionalFloat(r.Cost, 2),
+	}
+}
+
+// NewUsageReport sums the bases processed by completed workflows per month,
+// process, project, and status, and prices them. Rows are ordered by month,
+// process, project, and then status.
+func NewUsageReport(workflows []Workflow, options UsageOptions) UsageReport {
+	report := UsageReport{Rows: []UsageRow{}, Totals: []UsageRow{}}
+
+	type rowKey struct {
+		month   string
+		process string
+		project string
+		status  Status
+	}
+
+	rows := map[rowKey]*UsageRow{}
+	totals := map[Status]*UsageRow{}
+
+	for _, workflow := range workflows {
+		if !workflow.Status.IsTerminal() {
+			report.Incomplete++
+			continue
+		}
+
+		key := rowKey{
+			month:   SummaryPeriod(SummaryPeriodMonth).key(workflow.CreatedDate),
+			process: workflow.Process,
+			project: DescriptionPrefix(workflow.Description, options.PrefixDelimiter),
+			status:  workflow.Status,
+		}
+
+		row, ok := rows[key]
+
+		if !ok {
+			row = &UsageRow{
+				Month:   key.month,
+				Process: key.process,
+				Project: key.project,
+				Status:  key.status.String(),
+			}
+
+			if price, ok := options.Prices.Price(key.process); ok {
+				row.PricePerGbase = &price
+			}
+
+			rows[key] = row
+		}
+
+		row.Workflows++
+		row.BasesProcessed += workflow.BasesProcessed
+	}
+
+	keys := slices.SortedFunc(maps.Keys(rows), func(a rowKey, b rowKey) int {
+		return cmp.Or(
+			cmp.Compare(a.month, b.month),
+			cmp.Compare(a.process, b.process),
+			cmp.Compare(a.project, b.project),
+			cmp.Compare(a.status, b.status),
+		)
+	})
+
+	for _, key := range keys {
+		row := rows[key]
+
+		if row.PricePerGbase != nil {
+			cost := gigabases(row.BasesProcessed) * *row.PricePerGbase
+			row.Cost = &cost
+		}
+
+		report.Rows = append(report.Rows, *row)
+
+		total, ok := totals[key.status]
+
+		if !ok {
+			cost := 0.0
+			total = &UsageRow{Status: row.Status, Cost: &cost}
+			totals[key.status] = total
+		}
+
+		total.Workflows += row.Workflows
+		total.BasesProcessed += row.BasesProcessed
+
+		if row.Cost == nil {
+			total.Cost = nil
+		} else if total.Cost != nil {
+			*total.Cost += *row.Cost
+		}
+	}
+
+	for _, status := range slices.Sorted(maps.Keys(totals)) {
+		report.Totals = append(report.Totals, *totals[status])
+	}
+
+	return report
+}
+
+// WriteUsageReport writes the usage report as tables, CSV, or JSON.
+//
+// The table format has a table of the rows followed by a table of the totals
+// per status. The CSV format only has the rows, which sum to the totals.
+func WriteUsageReport(w io.Writer, format OutputFormat, report UsageReport) error {
+	switch format {
+	case OutputFormatTable:
+		return writeUsageTables(w, report)
+	case OutputFormatCSV:
+		return writeUsageCSV(w, report)
+	case OutputFormatJSON:
+		return writeJSON(w, report)
+	default:
+		return fmt.Errorf("invalid usage output format: %q", format)
+	}
+}
+
+func writeUsageTables(w io.Writer, report UsageReport) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "MONTH\tPROCESS\tPROJECT\tSTATUS\tWORKFLOWS\tGBASES\tPRICE/GBASE\tCOST")
+
+	for _, row := range report.Rows {
+		fmt.Fprintln(tw, strings.Join([]string{
+			row.Month,
+			valueOrDash(row.Process),
+			valueOrDash(row.Project),
+			row.Status,
+			fmt.Sprint(row.Workflows),
+			formatGigabases(row.BasesProcessed),
+			valueOrDash(formatOptionalFloat(row.PricePerGbase, -1)),
+			valueOrDash(formatOptionalFloat(row.Cost, 2)),
+		}, "\t"))
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	if len(report.Totals) > 0 {
+		fmt.Fprintln(w)
+
+		fmt.Fprintln(tw, "STATUS\tWORKFLOWS\tGBASES\tCOST")
+
+		for _, total := range report.Totals {
+			fmt.Fprintln(tw, strings.Join([]string{
+				total.Status,
+				fmt.Sprint(total.Workflows),
+				formatGigabases(total.BasesProcessed),
+				valueOrDash(formatOptionalFloat(total.Cost, 2)),
+			}, "\t"))
+		}
+
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeUsageCSV(w io.Writer, report UsageReport) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(usageRowColumns); err != nil {
+		return err
+	}
+
+	for _, row := range report.Rows {
+		if err := writer.Write(row.values()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+func gigabases(bases uint64) float64 {
+	return float64(bases) / 1e9
+}
+
+// formatOptionalFloat formats the value with the given precision (see
+// `strconv.FormatFloat`), or returns an empty string if it is unset.
+func formatOptionalFloat(v *float64, precision int) string {
+	if v == nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(*v, 'f', precision, 64)
+}
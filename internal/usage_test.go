@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func newUsageTestWorkflows() []Workflow {
+	january := time.Date(2024, 1, 31, 23, 0, 0, 0, time.UTC)
+	february := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	return []Workflow{
+		{ID: 1, Status: StatusSuccess, CreatedDate: january, Process: "gatk", Description: "PROJ1-a", BasesProcessed: 1_000_000_000},
+		{ID: 2, Status: StatusSuccess, CreatedDate: january, Process: "gatk", Description: "PROJ1-b", BasesProcessed: 2_000_000_000},
+		{ID: 3, Status: StatusFailed, CreatedDate: january, Process: "gatk", Description: "PROJ1-c", BasesProcessed: 500_000_000},
+		{ID: 4, Status: StatusCancelled, CreatedDate: february, Process: "gatk", Description: "PROJ2-a", BasesProcessed: 250_000_000},
+		{ID: 5, Status: StatusSuccess, CreatedDate: february, Process: "other", Description: "PROJ2-b", BasesProcessed: 4_000_000_000},
+		{ID: 6, Status: StatusWorking, CreatedDate: february, Process: "gatk", Description: "PROJ2-c", BasesProcessed: 100_000_000},
+	}
+}
+
+func TestNewUsageReport(t *testing.T) {
+	ptr := func(v float64) *float64 { return &v }
+
+	report := NewUsageReport(newUsageTestWorkflows(), UsageOptions{
+		PrefixDelimiter: "-",
+		Prices:          Prices{"gatk": 0.5},
+	})
+
+	expected := UsageReport{
+		Rows: []UsageRow{
+			{Month: "2024-01", Process: "gatk", Project: "PROJ1", Status: "success", Workflows: 2, BasesProcessed: 3_000_000_000, PricePerGbase: ptr(0.5), Cost: ptr(1.5)},
+			{Month: "2024-01", Process: "gatk", Project: "PROJ1", Status: "failed", Workflows: 1, BasesProcessed: 500_000_000, PricePerGbase: ptr(0.5), Cost: ptr(0.25)},
+			{Month: "2024-02", Process: "gatk", Project: "PROJ2", Status: "cancelled", Workflows: 1, BasesProcessed: 250_000_000, PricePerGbase: ptr(0.5), Cost: ptr(0.125)},
+			{Month: "2024-02", Process: "other", Project: "PROJ2", Status: "success", Workflows: 1, BasesProcessed: 4_000_000_000},
+		},
+		Totals: []UsageRow{
+			{Status: "success", Workflows: 3, BasesProcessed: 7_000_000_000},
+			{Status: "failed", Workflows: 1, BasesProcessed: 500_000_000, Cost: ptr(0.25)},
+			{Status: "cancelled", Workflows: 1, BasesProcessed: 250_000_000, Cost: ptr(0.125)},
+		},
+		Incomplete: 1,
+	}
+
+	if diff := cmp.Diff(report, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestPricesPrice(t *testing.T) {
+	test := func(t testing.TB, prices Prices, process string, expectedPrice float64, expectedOK bool) {
+		t.Helper()
+
+		price, ok := prices.Price(process)
+
+		if price != expectedPrice || ok != expectedOK {
+			t.Errorf("%q: expected (%v, %v), got (%v, %v)", process, expectedPrice, expectedOK, price, ok)
+		}
+	}
+
+	prices := Prices{"gatk": 0.5, "default": 0.25}
+
+	test(t, prices, "gatk", 0.5, true)
+	test(t, prices, "other", 0.25, true)
+	test(t, Prices{"gatk": 0.5}, "other", 0, false)
+	test(t, nil, "gatk", 0, false)
+}
+
+func TestWriteUsageReportWithTable(t *testing.T) {
+	report := NewUsageReport(newUsageTestWorkflows(), UsageOptions{
+		PrefixDelimiter: "-",
+		Prices:          Prices{"gatk": 0.5},
+	})
+
+	var buf bytes.Buffer
+
+	if err := WriteUsageReport(&buf, OutputFormatTable, report); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"MONTH    PROCESS  PROJECT  STATUS     WORKFLOWS  GBASES  PRICE/GBASE  COST\n" +
+		"2024-01  gatk     PROJ1    success    2          3.00    0.5          1.50\n" +
+		"2024-01  gatk     PROJ1    failed     1          0.50    0.5          0.25\n" +
+		"2024-02  gatk     PROJ2    cancelled  1          0.25    0.5          0.12\n" +
+		"2024-02  other    PROJ2    success    1          4.00    -            -\n" +
+		"\n" +
+		"STATUS     WORKFLOWS  GBASES  COST\n" +
+		"success    3          7.00    -\n" +
+		"failed     1          0.50    0.25\n" +
+		"cancelled  1          0.25    0.12\n"
+
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestWriteUsageReportWithCSV(t *testing.T) {
+	report := NewUsageReport(newUsageTestWorkflows(), UsageOptions{
+		PrefixDelimiter: "-",
+		Prices:          Prices{"default": 0.29},
+	})
+
+	var buf bytes.Buffer
+
+	if err := WriteUsageReport(&buf, OutputFormatCSV, report); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "" +
+		"month,process,project,status,workflows,basesProcessed,pricePerGbase,cost\n" +
+		"2024-01,gatk,PROJ1,success,2,3000000000,0.29,0.87\n" +
+		"2024-01,gatk,PROJ1,failed,1,500000000,0.29,0.14\n" +
+		"2024-02,gatk,PROJ2,cancelled,1,250000000,0.29,0.07\n" +
+		"2024-02,other,PROJ2,success,1,4000000000,0.29,1.16\n"
+
+	if diff := cmp.Diff(buf.String(), expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if err := WriteUsageReport(&buf, OutputFormatYAML, report); err == nil {
+		t.Error("expected error for an unsupported output format")
+	}
+}
+
+func TestUsageOptionsFromFlags(t *testing.T) {
+	newFlags := func(args ...string) *pflag.FlagSet {
+		t.Helper()
+
+		flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+		flags.String("prefix-delimiter", DefaultPrefixDelimiter, "")
+		flags.Float64("price-per-gbase", 0, "")
+
+		if err := flags.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+
+		return flags
+	}
+
+	prices := Prices{"gatk": 0.5, "default": 0.25}
+
+	options, err := UsageOptionsFromFlags(newFlags("--price-per-gbase", "0.3", "--prefix-delimiter", "_"), prices)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := UsageOptions{PrefixDelimiter: "_", Prices: Prices{"gatk": 0.5, "default": 0.3}}
+
+	if diff := cmp.Diff(options, expected); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if prices["default"] != 0.25 {
+		t.Error("expected the given prices to be unchanged")
+	}
+
+	options, err = UsageOptionsFromFlags(newFlags(), nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff(options, UsageOptions{PrefixDelimiter: "-", Prices: Prices{}}); diff != "" {
+		t.Errorf("mismatch (-actual, +expected):\n%s", diff)
+	}
+
+	if _, err := UsageOptionsFromFlags(newFlags("--price-per-gbase", "-1"), nil); err == nil {
+		t.Error("expected error for a negative price")
+	}
+}
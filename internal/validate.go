@@ -0,0 +1,254 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/spf13/pflag"
+)
+
+const (
+	minContainerNameLength = 3
+	maxContainerNameLength = 63
+
+	maxBlobNameLength       = 1024
+	maxBlobNamePathSegments = 254
+	maxOutputBasenameLength = 255
+)
+
+// https://learn.microsoft.com/en-us/rest/api/storageservices/naming-and-referencing-containers--blobs--and-metadata#container-names
+var containerNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+var outputBasenamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// ValidationError is a problem with a single configuration field. The field
+// is referred to by its flag name.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field, e.Message)
+}
+
+// ValidationErrors is a list of all problems found in a configuration.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+
+	return strings.Join(messages, "\n")
+}
+
+func (e *ValidationErrors) add(field string, format string, args ...any) {
+	*e = append(*e, &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// errOrNil returns nil if there are no errors. This avoids returning a typed
+// nil as a non-nil error.
+func (e ValidationErrors) errOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// ValidateSubmitConfig checks the submit configuration offline, i.e., before
+// any SAS is generated or API request is sent.
+//
+// All problems are reported as `ValidationErrors`.
+func ValidateSubmitConfig(config SubmitConfig) error {
+	errs := ValidationErrors{}
+
+	errs = append(errs, validateServiceConfig(config.Service)...)
+
+	if len(strings.TrimSpace(config.Process.Name)) == 0 {
+		errs.add("process-name", "missing value")
+	}
+
+	errs = append(errs, validateStorageConfig(config.Input.Storage, "input")...)
+
+	if err := ValidateBlobName(config.Input.BlobName); err != nil {
+		errs.add("input-blob-name", "%v", err)
+	}
+
+	errs = append(errs, validateStorageConfig(config.Output.Storage, "output")...)
+
+	if len(config.Output.Basename) > 0 {
+		if err := ValidateOutputBasename(config.Output.Basename); err != nil {
+			errs.add("output-basename", "%v", err)
+		}
+	}
+
+	errs = append(errs, validateOptionalArgsConfig(config.OptionalArgs)...)
+
+	return errs.errOrNil()
+}
+
+// ValidateSubmitConfigFromFlags builds the submit configuration from the
+// flags and validates it. Invalid flag values (see `SubmitConfigFromFlags`)
+// are reported with all other problems as `ValidationErrors`, where a field
+// with an invalid value is not checked further.
+func ValidateSubmitConfigFromFlags(flags *pflag.FlagSet) (SubmitConfig, error) {
+	config, err := SubmitConfigFromFlags(flags)
+
+	errs := ValidationErrors{}
+
+	if err != nil && !errors.As(err, &errs) {
+		return config, err
+	}
+
+	invalidFields := map[string]bool{}
+
+	for _, err := range errs {
+		invalidFields[err.Field] = true
+	}
+
+	var validationErrs ValidationErrors
+
+	if err := ValidateSubmitConfig(config); errors.As(err, &validationErrs) {
+		for _, err := range validationErrs {
+			if !invalidFields[err.Field] {
+				errs = append(errs, err)
+			}
+		}
+	}
+
+	return config, errs.errOrNil()
+}
+
+func validateServiceConfig(config ServiceConfig) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if err := ValidateBaseURL(config.BaseURL); err != nil {
+		errs.add("base-url", "%v", err)
+	}
+
+	if len(config.AccessKey) == 0 && !config.Replay {
+		errs.add("access-key", "missing value")
+	}
+
+	return errs
+}
+
+func validateStorageConfig(config StorageConfig, prefix string) ValidationErrors {
+	errs := ValidationErrors{}
+
+	connectionStringField := fmt.Sprintf("%s-storage-connection-string", prefix)
+
+	if len(config.AccountName) == 0 {
+		errs.add(connectionStringField, "missing AccountName")
+	}
+
+	// An empty mode is a shared key.
+	if len(config.Auth) > 0 {
+		if _, err := ParseStorageAuthMode(string(config.Auth)); err != nil {
+			errs.add(fmt.Sprintf("%s-storage-auth", prefix), "%v", err)
+		}
+	}
+
+	if !config.Auth.UsesTokenCredential() && len(config.AccountKey) == 0 && len(config.SharedAccessSignature) == 0 {
+		errs.add(connectionStringField, "missing AccountKey or SharedAccessSignature")
+	}
+
+	if err := ValidateContainerName(config.ContainerName); err != nil {
+		errs.add(fmt.Sprintf("%s-storage-container-name", prefix), "%v", err)
+	}
+
+	return errs
+}
+
+func validateOptionalArgsConfig(config OptionalArgsConfig) ValidationErrors {
+	errs := ValidationErrors{}
+
+	if _, err := ParseReferenceConfidenceMode(string(config.EmitRefConfidence)); err != nil {
+		errs.add("emit-ref-confidence", "%v", err)
+	}
+
+	return errs
+}
+
+// ValidateContainerName checks the Azure Storage container naming rules: 3-63
+// lowercase letters, numbers, and single hyphens, starting and ending with a
+// letter or number.
+func ValidateContainerName(s string) error {
+	if len(s) == 0 {
+		return errors.New("missing value")
+	}
+
+	if len(s) < minContainerNameLength || len(s) > maxContainerNameLength {
+		return fmt.Errorf(
+			"invalid container name %q: must be %d-%d characters long",
+			s,
+			minContainerNameLength,
+			maxContainerNameLength,
+		)
+	}
+
+	if !containerNamePattern.MatchString(s) {
+		return fmt.Errorf(
+			"invalid container name %q: must contain only lowercase letters, numbers, and single hyphens and start and end with a letter or number",
+			s,
+		)
+	}
+
+	return nil
+}
+
+// ValidateBlobName checks the Azure Storage blob naming rules: 1-1024
+// characters, no control characters, at most 254 path segments, and not
+// ending with a dot or slash.
+func ValidateBlobName(s string) error {
+	if len(s) == 0 {
+		return errors.New("missing value")
+	}
+
+	if len(s) > maxBlobNameLength {
+		return fmt.Errorf("invalid blob name: must be at most %d characters long", maxBlobNameLength)
+	}
+
+	if strings.IndexFunc(s, unicode.IsControl) != -1 {
+		return fmt.Errorf("invalid blob name %q: must not contain control characters", s)
+	}
+
+	if strings.HasSuffix(s, ".") || strings.HasSuffix(s, "/") {
+		return fmt.Errorf("invalid blob name %q: must not end with a dot or slash", s)
+	}
+
+	if strings.Count(s, "/")+1 > maxBlobNamePathSegments {
+		return fmt.Errorf(
+			"invalid blob name %q: must have at most %d path segments",
+			s,
+			maxBlobNamePathSegments,
+		)
+	}
+
+	return nil
+}
+
+// ValidateOutputBasename checks that the basename only contains letters,
+// numbers, dots, underscores, and hyphens, i.e., is safe to use as a blob
+// name prefix.
+func ValidateOutputBasename(s string) error {
+	if len(s) > maxOutputBasenameLength {
+		return fmt.Errorf("invalid basename: must be at most %d characters long", maxOutputBasenameLength)
+	}
+
+	if !outputBasenamePattern.MatchString(s) {
+		return fmt.Errorf(
+			"invalid basename %q: must contain only letters, numbers, dots, underscores, and hyphens",
+			s,
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package internal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/spf13/pflag"
+)
+
+func newValidSubmitConfig() SubmitConfig {
+	return SubmitConfig{
+		Service: ServiceConfig{
+			BaseURL:   "https://example.com",
+			AccessKey: "secret",
+		},
+		Input: InputConfig{
+			Storage: StorageConfig{
+				AccountName:   "input",
+				AccountKey:    "bXNnZW5jdGw=",
+				ContainerName: "data",
+			},
+			BlobName: "samples/sample.bam",
+		},
+		Process: ProcessConfig{
+			Name: "snapgatk-20190409_1",
+			Args: "R=hg38m1x",
+		},
+		Output: OutputConfig{
+			Storage: StorageConfig{
+				AccountName:   "output",
+				AccountKey:    "bXNnZW5jdGw=",
+				ContainerName: "results",
+			},
+			Basename: "sample",
+		},
+		OptionalArgs: OptionalArgsConfig{
+			EmitRefConfidence: ReferenceConfidenceModeGVCF,
+			BgzipOutput:       true,
+		},
+	}
+}
+
+func TestValidateSubmitConfig(t *testing.T) {
+	if err := ValidateSubmitConfig(newValidSubmitConfig()); err != nil {
+		t.Errorf("unexpected failure: %v", err)
+	}
+}
+
+func TestValidateSubmitConfigReportsAllErrors(t *testing.T) {
+	config := SubmitConfig{
+		Input: InputConfig{
+			Storage: StorageConfig{
+				AccountName:   "input",
+				AccountKey:    "bXNnZW5jdGw=",
+				ContainerName: "Data",
+			},
+			BlobName: "sample.bam/",
+		},
+		Process: ProcessConfig{
+			Name: "snap",
+		},
+		Output: OutputConfig{
+			Storage: StorageConfig{
+				ContainerName: "results",
+			},
+			Basename: "sample 1",
+		},
+		OptionalArgs: OptionalArgsConfig{
+			EmitRefConfidence: ReferenceConfidenceModeNone,
+			BgzipOutput:       true,
+		},
+	}
+
+	err := ValidateSubmitConfig(config)
+
+	var validationErrors ValidationErrors
+
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	actual := []string{}
+
+	for _, err := range validationErrors {
+		actual = append(actual, err.Field)
+	}
+
+	expected := []string{
+		"base-url",
+		"access-key",
+		"input-storage-container-name",
+		"input-blob-name",
+		"output-storage-connection-string",
+		"output-storage-connection-string",
+		"output-basename",
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("fields mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestValidateSubmitConfigFromFlagsWithSASConnectionString(t *testing.T) {
+	flags := newSubmitSpecFlagSet()
+	flags.String("base-url", "", "")
+	flags.String("access-key", "", "")
+
+	args := []string{
+		"--base-url", "https://example.com",
+		"--access-key", "secret",
+		"--process-name", "snapgatk-20190409_1",
+		"--input-storage-connection-string", "BlobEndpoint=https://input.blob.core.windows.net/;SharedAccessSignature=sv=2021-06-08&sig=secret",
+		"--input-storage-container-name", "data",
+		"--input-blob-name", "sample.bam",
+		"--output-storage-connection-string", "BlobEndpoint=https://output.blob.core.windows.net/;SharedAccessSignature=sv=2021-06-08&sig=secret",
+		"--output-storage-container-name", "results",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := ValidateSubmitConfigFromFlags(flags)
+
+	if err != nil {
+		t.Fatalf("unexpected failure: %v", err)
+	}
+
+	if config.Input.Storage.AccountName != "input" || config.Output.Storage.AccountName != "output" {
+		t.Errorf("expected account names from the blob endpoints, got %q and %q", config.Input.Storage.AccountName, config.Output.Storage.AccountName)
+	}
+}
+
+func TestValidateSubmitConfigWithEmptyProcessName(t *testing.T) {
+	config := newValidSubmitConfig()
+	config.Process.Name = " "
+
+	err := ValidateSubmitConfig(config)
+
+	if err == nil || !strings.HasPrefix(err.Error(), "process-name:") {
+		t.Errorf("expected process-name failure, got %v", err)
+	}
+}
+
+func TestValidateSubmitConfigWithOptionalArgs(t *testing.T) {
+	test := func(t testing.TB, mode ReferenceConfidenceMode, bgzipOutput bool, expected []string) {
+		t.Helper()
+
+		config := newValidSubmitConfig()
+		config.OptionalArgs = OptionalArgsConfig{EmitRefConfidence: mode, BgzipOutput: bgzipOutput}
+
+		actual := []string{}
+
+		var validationErrors ValidationErrors
+
+		if err := ValidateSubmitConfig(config); errors.As(err, &validationErrors) {
+			for _, err := range validationErrors {
+				actual = append(actual, err.Field)
+			}
+		}
+
+		if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+			t.Errorf("fields mismatch (-actual, +expected):\n%s", diff)
+		}
+	}
+
+	test(t, ReferenceConfidenceModeNone, false, []string{})
+	test(t, ReferenceConfidenceModeNone, true, []string{})
+	test(t, ReferenceConfidenceModeBPResolution, true, []string{})
+	test(t, "gvcf", false, []string{"emit-ref-confidence"})
+}
+
+func TestValidateSubmitConfigFromFlags(t *testing.T) {
+	flags := pflag.NewFlagSet("", pflag.ContinueOnError)
+
+	for _, name := range []string{
+		"base-url",
+		"access-key",
+		"region",
+		"process-name",
+		"process-args",
+		"input-storage-connection-string",
+		"input-storage-region",
+		"input-storage-container-name",
+		"input-blob-name",
+		"description",
+		"output-storage-connection-string",
+		"output-storage-region",
+		"output-storage-container-name",
+		"output-basename",
+		"emit-ref-confidence",
+	} {
+		flags.String(name, "", "")
+	}
+
+	flags.String("input-storage-auth", StorageAuthModeSharedKey, "")
+	flags.String("output-storage-auth", StorageAuthModeSharedKey, "")
+
+	for _, name := range []string{"output-overwrite", "output-include-log", "bgzip-output", "ignore-azure-region"} {
+		flags.Bool(name, false, "")
+	}
+
+	args := []string{
+		"--base-url", "https://example.com",
+		"--access-key", "secret",
+		"--process-name", "snapgatk-20190409_1",
+		"--input-storage-connection-string", "AccountName",
+		"--input-storage-container-name", "data",
+		"--input-blob-name", "sample.bam",
+		"--output-storage-connection-string", "AccountName=output;AccountKey=b3V0cHV0",
+		"--output-storage-auth", "password",
+		"--output-storage-container-name", "Results",
+		"--emit-ref-confidence", "gvcf",
+	}
+
+	if err := flags.Parse(args); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ValidateSubmitConfigFromFlags(flags)
+
+	var validationErrors ValidationErrors
+
+	if !errors.As(err, &validationErrors) {
+		t.Fatalf("expected ValidationErrors, got %v", err)
+	}
+
+	actual := []string{}
+
+	for _, err := range validationErrors {
+		actual = append(actual, err.Field)
+	}
+
+	expected := []string{
+		"input-storage-connection-string",
+		"output-storage-auth",
+		"emit-ref-confidence",
+		"output-storage-container-name",
+	}
+
+	if diff := cmp.Diff(actual, expected); len(diff) != 0 {
+		t.Errorf("fields mismatch (-actual, +expected):\n%s", diff)
+	}
+}
+
+func TestValidateContainerName(t *testing.T) {
+	test := func(t testing.TB, s string, ok bool) {
+		t.Helper()
+
+		err := ValidateContainerName(s)
+
+		if ok && err != nil {
+			t.Errorf("unexpected failure: s = %q: %v", s, err)
+		} else if !ok && err == nil {
+			t.Errorf("expected failure: s = %q", s)
+		}
+	}
+
+	test(t, "data", true)
+	test(t, "sample-01", true)
+	test(t, "abc", true)
+	test(t, strings.Repeat("a", 63), true)
+	test(t, "", false)
+	test(t, "ab", false)
+	test(t, strings.Repeat("a", 64), false)
+	test(t, "Data", false)
+	test(t, "-data", false)
+	test(t, "data-", false)
+	test(t, "da--ta", false)
+	test(t, "da_ta", false)
+}
+
+func TestValidateBlobName(t *testing.T) {
+	test := func(t testing.TB, s string, ok bool) {
+		t.Helper()
+
+		err := ValidateBlobName(s)
+
+		if ok && err != nil {
+			t.Errorf("unexpected failure: s = %q: %v", s, err)
+		} else if !ok && err == nil {
+			t.Errorf("expected failure: s = %q", s)
+		}
+	}
+
+	test(t, "sample.bam", true)
+	test(t, "a/b/sample.bam", true)
+	test(t, "", false)
+	test(t, strings.Repeat("a", 1025), false)
+	test(t, "sample.bam.", false)
+	test(t, "samples/", false)
+	test(t, "sample\n.bam", false)
+	test(t, strings.Repeat("a/", 254)+"a", false)
+}
+
+func TestValidateOutputBasename(t *testing.T) {
+	test := func(t testing.TB, s string, ok bool) {
+		t.Helper()
+
+		err := ValidateOutputBasename(s)
+
+		if ok && err != nil {
+			t.Errorf("unexpected failure: s = %q: %v", s, err)
+		} else if !ok && err == nil {
+			t.Errorf("expected failure: s = %q", s)
+		}
+	}
+
+	test(t, "sample", true)
+	test(t, "sample_01.v2-final", true)
+	test(t, "sample 01", false)
+	test(t, "samples/01", false)
+	test(t, strings.Repeat("a", 256), false)
+}
@@ -2,6 +2,7 @@ package internal
 
 import (
 	"fmt"
+	"log/slog"
 	"time"
 )
 
@@ -90,3 +91,22 @@ type NewWorkflow struct {
 	OptionalArgs      NewWorkflowOptionalArgs
 	IgnoreAzureRegion bool
 }
+
+// LogValue masks the SAS in the payload.
+func (w NewWorkflow) LogValue() slog.Value {
+	inputArgs := w.InputArgs
+	inputArgs.BlobNamesWithSAS = Redact(inputArgs.BlobNamesWithSAS)
+
+	outputArgs := w.OutputArgs
+	outputArgs.ContainerSAS = maskNonEmpty(outputArgs.ContainerSAS)
+
+	return slog.GroupValue(
+		slog.String("process", w.Process),
+		slog.String("processArgs", w.ProcessArgs),
+		slog.String("description", w.Description),
+		slog.Any("inputArgs", inputArgs),
+		slog.Any("outputArgs", outputArgs),
+		slog.Any("optionalArgs", w.OptionalArgs),
+		slog.Bool("ignoreAzureRegion", w.IgnoreAzureRegion),
+	)
+}